@@ -3,41 +3,43 @@ package commands
 import (
 	"fmt"
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
-	"github.com/codecrafters-io/redis-starter-go/app/store"
 	"strings"
 )
 
 // NoOpCommand 空实现
 type NoOpCommand struct{}
 
-func (c *NoOpCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
-	return resp.EncodeSimpleString("OK"), nil
+func (c *NoOpCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	return resp.OK, nil
 }
 
 // PingCommand 处理 PING 命令
 type PingCommand struct {
 }
 
-func (c *PingCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
-	if len(args) > 0 {
-		return "", fmt.Errorf("PING command takes no arguments")
+func (c *PingCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) > 1 {
+		return nil, wrongArgs("ping")
+	}
+	if len(args) == 1 {
+		return resp.BulkString(args[0]), nil
 	}
-	return resp.EncodeSimpleString("PONG"), nil
+	return resp.SimpleString("PONG"), nil
 }
 
 // EchoCommand 处理 ECHO 命令
 type EchoCommand struct{}
 
-func (c *EchoCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *EchoCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) != 1 {
-		return "", fmt.Errorf("ECHO command requires exactly one argument")
+		return nil, wrongArgs("echo")
 	}
-	return resp.EncodeBulkString(args[0]), nil
+	return resp.BulkString(args[0]), nil
 }
 
 type InfoCommand struct{}
 
-func (c *InfoCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *InfoCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	section := ""
 	if len(args) > 0 {
 		section = strings.ToLower(args[0])
@@ -47,39 +49,25 @@ func (c *InfoCommand) Handle(ctx *ConnectionContext, args []string) (interface{}
 		info := fmt.Sprintf("role:%s\r\n", GetServerRole()) +
 			fmt.Sprintf("master_replid:%s\r\n", GetMasterReplID()) +
 			fmt.Sprintf("master_repl_offset:%d\r\n", GetMasterReplOffset())
-		return resp.EncodeBulkString(info), nil
+		return resp.Verbatim{Format: "txt", Text: info}, nil
 	}
-	// 默认返回空或其他 sections
-	return "", nil
+	// 其他 section 暂不支持，返回空文本
+	return resp.Verbatim{Format: "txt"}, nil
 }
 
 type TypeCommand struct {
-	stringOps store.StringOps
-	listOps   store.ListOps
-	streamOps store.StreamOps
+	keyspace *Keyspace
 }
 
-func NewTypeCommand(s store.StringOps, l store.ListOps, ss store.StreamOps) *TypeCommand {
+func NewTypeCommand(k *Keyspace) *TypeCommand {
 	return &TypeCommand{
-		stringOps: s,
-		listOps:   l,
-		streamOps: ss,
+		keyspace: k,
 	}
 }
 
-func (c *TypeCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *TypeCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) != 1 {
-		return "", fmt.Errorf("TYPE command requires exactly one argument")
-	}
-	key := args[0]
-	if _, exists := c.stringOps.GetString(key); exists {
-		return resp.EncodeSimpleString("string"), nil
-	}
-	if c.listOps.Exists(key) {
-		return resp.EncodeSimpleString("list"), nil
-	}
-	if c.streamOps.Exists(key) {
-		return resp.EncodeSimpleString("stream"), nil
+		return nil, wrongArgs("type")
 	}
-	return resp.EncodeSimpleString("none"), nil
+	return resp.SimpleString(c.keyspace.Type(args[0])), nil
 }
@@ -0,0 +1,227 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnblocked CLIENT UNBLOCK ... ERROR 时返回给被阻塞客户端的错误
+	ErrUnblocked = &resp.Error{Code: "UNBLOCKED", Message: "client unblocked via CLIENT UNBLOCK"}
+	// errUnblockedTimeout CLIENT UNBLOCK ... TIMEOUT 的取消原因，按超时处理
+	errUnblockedTimeout = errors.New("client unblocked via CLIENT UNBLOCK")
+)
+
+// 所有在线客户端，按 ID 索引
+var (
+	clientsMu sync.Mutex
+	clients   = make(map[int64]*ConnectionContext)
+)
+
+func registerClient(c *ConnectionContext) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	clients[c.ID] = c
+}
+
+func unregisterClient(c *ConnectionContext) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	delete(clients, c.ID)
+}
+
+func lookupClient(id int64) (*ConnectionContext, bool) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	c, ok := clients[id]
+	return c, ok
+}
+
+// blockingError 将阻塞操作返回的错误转换为命令结果
+// 超时类的取消（CLIENT UNBLOCK TIMEOUT、连接断开）返回 timedOut=true，其他错误原样返回
+func blockingError(err error) (timedOut bool, _ error) {
+	switch {
+	case err == nil:
+		return false, nil
+	case errors.Is(err, errUnblockedTimeout), errors.Is(err, context.Canceled):
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+// parseBlockTimeout 解析阻塞命令的超时参数（秒，可以是小数），0 表示无限阻塞
+func parseBlockTimeout(s string) (time.Duration, error) {
+	sec, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(sec) || math.IsInf(sec, 0) {
+		return 0, fmt.Errorf("timeout is not a float or out of range")
+	}
+	if sec < 0 {
+		return 0, fmt.Errorf("timeout is negative")
+	}
+	// float64(math.MaxInt64) 恰好是 2^63，等于它也已经溢出
+	if sec*float64(time.Second) >= float64(math.MaxInt64) {
+		return 0, fmt.Errorf("timeout is out of range")
+	}
+	return time.Duration(sec * float64(time.Second)), nil
+}
+
+type ClientCommand struct{}
+
+func (c *ClientCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 {
+		return nil, wrongArgs("client")
+	}
+	switch strings.ToUpper(args[0]) {
+	case "ID":
+		return resp.Integer(ctx.ID), nil
+	case "GETNAME":
+		name := ctx.Name()
+		if name == "" {
+			return resp.Null{}, nil
+		}
+		return resp.BulkString(name), nil
+	case "SETNAME":
+		if len(args) != 2 {
+			return nil, wrongArgs("client|setname")
+		}
+		if err := validateClientName(args[1]); err != nil {
+			return nil, err
+		}
+		ctx.SetName(args[1])
+		return resp.OK, nil
+	case "LIST":
+		return resp.Verbatim{Format: "txt", Text: clientList()}, nil
+	case "UNBLOCK":
+		return c.unblock(args[1:])
+	default:
+		return nil, unknownSubcommand("CLIENT", args[0])
+	}
+}
+
+// validateClientName 检查 CLIENT SETNAME 和 HELLO SETNAME 设置的名称
+func validateClientName(name string) error {
+	if strings.ContainsAny(name, " \n") {
+		return fmt.Errorf("Client names cannot contain spaces, newlines or special characters.")
+	}
+	return nil
+}
+
+// unblock 处理 CLIENT UNBLOCK id [TIMEOUT|ERROR]
+func (c *ClientCommand) unblock(args []string) (resp.Reply, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, wrongArgs("client|unblock")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	cause := errUnblockedTimeout
+	if len(args) == 2 {
+		switch strings.ToUpper(args[1]) {
+		case "TIMEOUT":
+		case "ERROR":
+			cause = ErrUnblocked
+		default:
+			return nil, fmt.Errorf("CLIENT UNBLOCK reason should be TIMEOUT or ERROR")
+		}
+	}
+	target, ok := lookupClient(id)
+	if !ok || !target.Unblock(cause) {
+		return resp.Integer(0), nil
+	}
+	return resp.Integer(1), nil
+}
+
+// clientList 生成 CLIENT LIST 的输出，每个客户端一行
+func clientList() string {
+	clientsMu.Lock()
+	list := make([]*ConnectionContext, 0, len(clients))
+	for _, c := range clients {
+		list = append(list, c)
+	}
+	clientsMu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	var sb strings.Builder
+	now := time.Now()
+	for _, c := range list {
+		flags := "N"
+		if c.IsBlocked() {
+			flags = "b"
+		}
+		fmt.Fprintf(&sb, "id=%d addr=%s name=%s age=%d flags=%s\n",
+			c.ID, c.Addr, c.Name(), int(now.Sub(c.CreatedAt).Seconds()), flags)
+	}
+	return sb.String()
+}
+
+// serverVersion 是 HELLO 返回的服务器版本，表示兼容的 Redis 版本
+const serverVersion = "7.4.0"
+
+// HelloCommand 处理 HELLO [protover [AUTH username password] [SETNAME clientname]]
+// 切换连接的协议版本，并返回服务器和连接的信息
+type HelloCommand struct{}
+
+func (c *HelloCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	protocol := ctx.Protocol
+	if len(args) > 0 {
+		ver, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("Protocol version is not an integer or out of range")
+		}
+		if ver < 2 || ver > 3 {
+			return nil, resp.NewError("NOPROTO", "sorry, this protocol version is not supported.")
+		}
+		protocol = ver
+	}
+
+	// 先检查所有选项，全部合法后再生效
+	var user, name string
+	auth, setName := false, false
+	for i := 1; i < len(args); i++ {
+		switch {
+		case strings.ToUpper(args[i]) == "AUTH" && i+2 < len(args):
+			auth, user = true, args[i+1]
+			i += 2
+		case strings.ToUpper(args[i]) == "SETNAME" && i+1 < len(args):
+			setName, name = true, args[i+1]
+			if err := validateClientName(name); err != nil {
+				return nil, err
+			}
+			i++
+		default:
+			return nil, fmt.Errorf("Syntax error in HELLO option '%s'", args[i])
+		}
+	}
+	// 服务器没有配置密码，与 Redis 一样只有 default 用户，任意密码都能通过
+	if auth && user != "default" {
+		return nil, resp.NewError("WRONGPASS", "invalid username-password pair or user is disabled.")
+	}
+	if setName {
+		ctx.SetName(name)
+	}
+	ctx.Protocol = protocol
+
+	role := "master"
+	if GetServerRole() == "slave" {
+		role = "replica"
+	}
+	return resp.Map{
+		resp.BulkString("server"), resp.BulkString("redis"),
+		resp.BulkString("version"), resp.BulkString(serverVersion),
+		resp.BulkString("proto"), resp.Integer(protocol),
+		resp.BulkString("id"), resp.Integer(ctx.ID),
+		resp.BulkString("mode"), resp.BulkString("standalone"),
+		resp.BulkString("role"), resp.BulkString(role),
+		resp.BulkString("modules"), resp.Array{},
+	}, nil
+}
@@ -1,16 +1,21 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
 	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"io"
 	"net"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // CommandHandler 定义命令处理接口
+// 回复由连接按客户端的协议版本编码；返回的错误作为错误回复，*resp.Error 携带错误码，其他错误使用 ERR
 type CommandHandler interface {
-	Handle(ctx *ConnectionContext, args []string) (interface{}, error)
+	Handle(ctx *ConnectionContext, args []string) (resp.Reply, error)
 }
 
 // CommandRegistry 存储命令名称到处理器的映射
@@ -20,10 +25,30 @@ type CommandRegistry map[string]CommandHandler
 var stringStore = store.NewStringStore()
 var listStore = store.NewListStore()
 var streamStore = store.NewStreamStore()
+var hashStore = store.NewHashStore()
+var setStore = store.NewSetStore()
+var zsetStore = store.NewZSetStore()
+
+// keyspace 按 TYPE 命令的检查顺序聚合所有存储
+var keyspace = NewKeyspace(
+	typedStore{"string", stringStore},
+	typedStore{"list", listStore},
+	typedStore{"stream", streamStore},
+	typedStore{"hash", hashStore},
+	typedStore{"set", setStore},
+	typedStore{"zset", zsetStore},
+)
+
+// commandLock 与 Redis 单线程执行命令一样让命令逐个执行
+// 各存储的锁只保护自己的数据；类型检查、删除其他类型的同名键和写入必须在这把锁下一起完成，
+// 否则并发的 SET 与 HSET 可能让同一个键同时存在两种类型
+var commandLock sync.Mutex
 
 // Commands 注册命令
 var Commands = CommandRegistry{
 	"PING":     &PingCommand{},
+	"HELLO":    &HelloCommand{},
+	"CLIENT":   &ClientCommand{},
 	"ECHO":     &EchoCommand{},
 	"COMMAND":  &NoOpCommand{}, // 空实现
 	"REPLCONF": &ReplconfCommand{},
@@ -32,7 +57,7 @@ var Commands = CommandRegistry{
 	"MULTI":    &MultiCommand{},
 	"EXEC":     &ExecCommand{},
 	"DISCARD":  &DiscardCommand{},
-	"SET":      NewSetCommand(stringStore),
+	"SET":      NewSetCommand(stringStore, keyspace),
 	"GET":      NewGetCommand(stringStore),
 	"INCR":     NewIncrCommand(stringStore),
 	"RPUSH":    NewRPushCommand(listStore),
@@ -41,10 +66,120 @@ var Commands = CommandRegistry{
 	"LLEN":     NewLLenCommand(listStore),
 	"LPOP":     NewLPopCommand(listStore),
 	"BLPOP":    NewBLPopCommand(listStore),
-	"TYPE":     NewTypeCommand(stringStore, listStore, streamStore),
+	"TYPE":     NewTypeCommand(keyspace),
+	"SORT":     NewSortCommand(keyspace, listStore, setStore, zsetStore, stringStore, hashStore),
+	"SORT_RO":  NewSortROCommand(keyspace, listStore, setStore, zsetStore, stringStore, hashStore),
 	"XADD":     NewXAddCommand(streamStore),
 	"XRANGE":   NewXRangeCommand(streamStore),
 	"XREAD":    NewXReadCommand(streamStore),
+	"XLEN":     NewXLenCommand(streamStore),
+	"XDEL":     NewXDelCommand(streamStore),
+	"XTRIM":    NewXTrimCommand(streamStore),
+
+	"XGROUP":     NewXGroupCommand(streamStore),
+	"XREADGROUP": NewXReadGroupCommand(streamStore),
+	"XACK":       NewXAckCommand(streamStore),
+	"XPENDING":   NewXPendingCommand(streamStore),
+	"XCLAIM":     NewXClaimCommand(streamStore),
+	"XAUTOCLAIM": NewXAutoClaimCommand(streamStore),
+	"XINFO":      NewXInfoCommand(streamStore),
+	"XREVRANGE":  NewXRevRangeCommand(streamStore),
+	"XSETID":     NewXSetIDCommand(streamStore),
+
+	"HSET":         NewHSetCommand(hashStore),
+	"HMSET":        NewHMSetCommand(hashStore),
+	"HSETNX":       NewHSetNXCommand(hashStore),
+	"HGET":         NewHGetCommand(hashStore),
+	"HMGET":        NewHMGetCommand(hashStore),
+	"HDEL":         NewHDelCommand(hashStore),
+	"HLEN":         NewHLenCommand(hashStore),
+	"HSTRLEN":      NewHStrLenCommand(hashStore),
+	"HEXISTS":      NewHExistsCommand(hashStore),
+	"HKEYS":        NewHKeysCommand(hashStore),
+	"HVALS":        NewHValsCommand(hashStore),
+	"HGETALL":      NewHGetAllCommand(hashStore),
+	"HINCRBY":      NewHIncrByCommand(hashStore),
+	"HINCRBYFLOAT": NewHIncrByFloatCommand(hashStore),
+	"HRANDFIELD":   NewHRandFieldCommand(hashStore),
+	"HSCAN":        NewHScanCommand(hashStore),
+	"HEXPIRE":      NewHExpireCommand(hashStore),
+	"HPEXPIRE":     NewHPExpireCommand(hashStore),
+	"HEXPIREAT":    NewHExpireAtCommand(hashStore),
+	"HPEXPIREAT":   NewHPExpireAtCommand(hashStore),
+	"HTTL":         NewHTTLCommand(hashStore),
+	"HPTTL":        NewHPTTLCommand(hashStore),
+	"HEXPIRETIME":  NewHExpireTimeCommand(hashStore),
+	"HPEXPIRETIME": NewHPExpireTimeCommand(hashStore),
+	"HPERSIST":     NewHPersistCommand(hashStore),
+	"HGETEX":       NewHGetExCommand(hashStore),
+	"HSETEX":       NewHSetExCommand(hashStore),
+	"HGETDEL":      NewHGetDelCommand(hashStore),
+
+	"SADD":        NewSAddCommand(setStore),
+	"SREM":        NewSRemCommand(setStore),
+	"SMEMBERS":    NewSMembersCommand(setStore),
+	"SISMEMBER":   NewSIsMemberCommand(setStore),
+	"SMISMEMBER":  NewSMIsMemberCommand(setStore),
+	"SCARD":       NewSCardCommand(setStore),
+	"SPOP":        NewSPopCommand(setStore),
+	"SRANDMEMBER": NewSRandMemberCommand(setStore),
+	"SMOVE":       NewSMoveCommand(setStore),
+	"SSCAN":       NewSScanCommand(setStore),
+	"SINTER":      NewSInterCommand(setStore),
+	"SUNION":      NewSUnionCommand(setStore),
+	"SDIFF":       NewSDiffCommand(setStore),
+	"SINTERSTORE": NewSInterStoreCommand(setStore, keyspace),
+	"SUNIONSTORE": NewSUnionStoreCommand(setStore, keyspace),
+	"SDIFFSTORE":  NewSDiffStoreCommand(setStore, keyspace),
+	"SINTERCARD":  NewSInterCardCommand(setStore),
+
+	"ZADD":     NewZAddCommand(zsetStore),
+	"ZINCRBY":  NewZIncrByCommand(zsetStore),
+	"ZSCORE":   NewZScoreCommand(zsetStore),
+	"ZMSCORE":  NewZMScoreCommand(zsetStore),
+	"ZRANK":    NewZRankCommand(zsetStore),
+	"ZREVRANK": NewZRevRankCommand(zsetStore),
+	"ZREM":     NewZRemCommand(zsetStore),
+	"ZCARD":    NewZCardCommand(zsetStore),
+	"ZCOUNT":   NewZCountCommand(zsetStore),
+	"ZSCAN":    NewZScanCommand(zsetStore),
+
+	"ZRANGE":           NewZRangeCommand(zsetStore),
+	"ZRANGESTORE":      NewZRangeStoreCommand(zsetStore, keyspace),
+	"ZREVRANGE":        NewZRevRangeCommand(zsetStore),
+	"ZRANGEBYSCORE":    NewZRangeByScoreCommand(zsetStore),
+	"ZREVRANGEBYSCORE": NewZRevRangeByScoreCommand(zsetStore),
+	"ZRANGEBYLEX":      NewZRangeByLexCommand(zsetStore),
+	"ZREVRANGEBYLEX":   NewZRevRangeByLexCommand(zsetStore),
+	"ZLEXCOUNT":        NewZLexCountCommand(zsetStore),
+	"ZREMRANGEBYRANK":  NewZRemRangeByRankCommand(zsetStore),
+	"ZREMRANGEBYSCORE": NewZRemRangeByScoreCommand(zsetStore),
+	"ZREMRANGEBYLEX":   NewZRemRangeByLexCommand(zsetStore),
+	"ZPOPMIN":          NewZPopMinCommand(zsetStore),
+	"ZPOPMAX":          NewZPopMaxCommand(zsetStore),
+	"BZPOPMIN":         NewBZPopMinCommand(zsetStore),
+	"BZPOPMAX":         NewBZPopMaxCommand(zsetStore),
+	"ZMPOP":            NewZMPopCommand(zsetStore),
+	"BZMPOP":           NewBZMPopCommand(zsetStore),
+	"ZUNION":           NewZUnionCommand(zsetStore, setStore),
+	"ZINTER":           NewZInterCommand(zsetStore, setStore),
+	"ZDIFF":            NewZDiffCommand(zsetStore, setStore),
+	"ZUNIONSTORE":      NewZUnionStoreCommand(zsetStore, setStore, keyspace),
+	"ZINTERSTORE":      NewZInterStoreCommand(zsetStore, setStore, keyspace),
+	"ZDIFFSTORE":       NewZDiffStoreCommand(zsetStore, setStore, keyspace),
+	"ZINTERCARD":       NewZInterCardCommand(zsetStore, setStore),
+	"ZRANDMEMBER":      NewZRandMemberCommand(zsetStore),
+
+	"GEOADD":               NewGeoAddCommand(zsetStore),
+	"GEOPOS":               NewGeoPosCommand(zsetStore),
+	"GEODIST":              NewGeoDistCommand(zsetStore),
+	"GEOHASH":              NewGeoHashCommand(zsetStore),
+	"GEOSEARCH":            NewGeoSearchCommand(zsetStore),
+	"GEOSEARCHSTORE":       NewGeoSearchStoreCommand(zsetStore, keyspace),
+	"GEORADIUS":            NewGeoRadiusCommand(zsetStore, keyspace),
+	"GEORADIUS_RO":         NewGeoRadiusROCommand(zsetStore),
+	"GEORADIUSBYMEMBER":    NewGeoRadiusByMemberCommand(zsetStore, keyspace),
+	"GEORADIUSBYMEMBER_RO": NewGeoRadiusByMemberROCommand(zsetStore),
 }
 
 // isWriteCommand 检查命令是否为写命令
@@ -56,23 +191,91 @@ func isWriteCommand(cmdName string) bool {
 		"LPUSH": true,
 		"LPOP":  true,
 		"BLPOP": true,
+		"SORT":  true,
 		"XADD":  true,
+		"XDEL":  true,
+		"XTRIM": true,
+
+		"XGROUP":     true,
+		"XREADGROUP": true,
+		"XACK":       true,
+		"XCLAIM":     true,
+		"XAUTOCLAIM": true,
+		"XSETID":     true,
+
+		"HSET":         true,
+		"HMSET":        true,
+		"HSETNX":       true,
+		"HDEL":         true,
+		"HINCRBY":      true,
+		"HINCRBYFLOAT": true,
+		"HEXPIRE":      true,
+		"HPEXPIRE":     true,
+		"HEXPIREAT":    true,
+		"HPEXPIREAT":   true,
+		"HPERSIST":     true,
+		"HGETEX":       true,
+		"HSETEX":       true,
+		"HGETDEL":      true,
+
+		"SADD":        true,
+		"SREM":        true,
+		"SPOP":        true,
+		"SMOVE":       true,
+		"SINTERSTORE": true,
+		"SUNIONSTORE": true,
+		"SDIFFSTORE":  true,
+
+		"ZADD":    true,
+		"ZINCRBY": true,
+		"ZREM":    true,
+
+		"ZRANGESTORE":      true,
+		"ZREMRANGEBYRANK":  true,
+		"ZREMRANGEBYSCORE": true,
+		"ZREMRANGEBYLEX":   true,
+		"ZPOPMIN":          true,
+		"ZPOPMAX":          true,
+		"BZPOPMIN":         true,
+		"BZPOPMAX":         true,
+		"ZMPOP":            true,
+		"BZMPOP":           true,
+		"ZUNIONSTORE":      true,
+		"ZINTERSTORE":      true,
+		"ZDIFFSTORE":       true,
+
+		"GEOADD":            true,
+		"GEOSEARCHSTORE":    true,
+		"GEORADIUS":         true,
+		"GEORADIUSBYMEMBER": true,
 	}
 	return writeCommands[cmdName]
 }
 
+// RDBResponse 是 PSYNC 的回复：FULLRESYNC 状态后紧跟 RDB 文件 $<len>\r\n<data>（末尾没有 \r\n）
 type RDBResponse struct {
-	Message string
+	Message resp.SimpleString
 	RDBData []byte
 }
 
-func (r *RDBResponse) String() string {
-	return r.Message
+func (r *RDBResponse) AppendRESP(b []byte, protocol int) []byte {
+	b = r.Message.AppendRESP(b, protocol)
+	b = append(b, '$')
+	b = strconv.AppendInt(b, int64(len(r.RDBData)), 10)
+	b = append(b, '\r', '\n')
+	return append(b, r.RDBData...)
 }
 
 // HandleConnection 处理客户端连接
 func HandleConnection(conn net.Conn) {
+	// 每个连接单独一个事务上下文
+	connCtx := NewConnectionContext()
+	connCtx.Addr = conn.RemoteAddr().String()
+
 	defer func(conn net.Conn) {
+		// 取消连接上下文，唤醒仍阻塞的命令
+		connCtx.Close()
+
 		err := conn.Close()
 		if err != nil {
 			fmt.Println("Error closing connection: ", err.Error())
@@ -82,18 +285,16 @@ func HandleConnection(conn net.Conn) {
 		RemoveReplicaConn(conn)
 	}(conn)
 
-	reader := resp.NewRESPReader(conn)
-	// 每个连接单独一个事务上下文
-	connCtx := NewConnectionContext()
+	queue := newCommandQueue()
+	go readCommands(conn, connCtx, queue)
 
 	for {
-		args, err := reader.ReadCommand()
-		if err != nil {
-			if err.Error() == "EOF" {
-				fmt.Println("Connection closed")
-				break
+		args, ok := queue.pop()
+		if !ok {
+			// 协议错误在之前的命令都回复之后再回复，然后关闭连接
+			if queue.err != nil {
+				writeReply(conn, connCtx, resp.ErrorReply(queue.err))
 			}
-			fmt.Println("Error parsing RESP: ", err.Error())
 			break
 		}
 
@@ -104,37 +305,38 @@ func HandleConnection(conn net.Conn) {
 		commandName := strings.ToUpper(args[0])
 		handler, exists := Commands[commandName]
 		if !exists {
-			respErr := resp.EncodeError("unknown command '" + commandName + "'")
-			conn.Write([]byte(respErr))
+			// 与 Redis 一样，事务中出现未知命令时 EXEC 放弃整个事务
+			if connCtx.InTransaction {
+				connCtx.TxAborted = true
+			}
+			writeReply(conn, connCtx, resp.ErrorReply(unknownCommand(args)))
+			continue
+		}
+
+		// 副本只读：拒绝客户端发来的写命令（主节点传播的命令不经过这里）
+		if GetServerRole() == "slave" && isWriteCommand(commandName) {
+			if connCtx.InTransaction {
+				connCtx.TxAborted = true
+			}
+			writeReply(conn, connCtx, errReadOnly)
 			continue
 		}
 
 		// 事务模式下且命令不是 MULTI/EXEC/DISCARD就排队
 		if connCtx.InTransaction && (commandName != "MULTI" && commandName != "EXEC" && commandName != "DISCARD") {
 			connCtx.QueuedCommands = append(connCtx.QueuedCommands, args)
-			conn.Write([]byte("+QUEUED\r\n"))
+			writeReply(conn, connCtx, resp.SimpleString("QUEUED"))
 			continue
 		}
 
 		// 执行命令处理
-		response, err := handler.Handle(connCtx, args[1:])
+		connCtx.resetPropagation()
+		response, err := runCommand(connCtx, commandName, handler, args)
 		if err != nil {
-			conn.Write([]byte(resp.EncodeError(err.Error())))
+			writeReply(conn, connCtx, resp.ErrorReply(err))
 			continue
 		}
-
-		switch v := response.(type) {
-		case string:
-			conn.Write([]byte(v))
-		case *RDBResponse:
-			conn.Write([]byte(v.Message))
-			rdbHeader := fmt.Sprintf("$%d\r\n", len(v.RDBData))
-			conn.Write([]byte(rdbHeader))
-			conn.Write(v.RDBData)
-		default:
-			// 处理未知类型
-			conn.Write([]byte(resp.EncodeError("Internal server error")))
-		}
+		writeReply(conn, connCtx, response)
 
 		// 如果是 PSYNC，添加为副本连接
 		if commandName == "PSYNC" {
@@ -142,7 +344,133 @@ func HandleConnection(conn net.Conn) {
 		}
 		// 非事务模式下，如果是写命令且成功，传播
 		if err == nil && !connCtx.InTransaction && isWriteCommand(commandName) {
-			PropagateWriteCommand(args)
+			for _, cmd := range connCtx.propagation(args) {
+				PropagateWriteCommand(cmd)
+			}
+		}
+	}
+}
+
+// runCommand 持有命令锁，检查命令访问的键类型后执行命令
+func runCommand(ctx *ConnectionContext, commandName string, handler CommandHandler, args []string) (resp.Reply, error) {
+	commandLock.Lock()
+	defer commandLock.Unlock()
+	return execCommand(ctx, commandName, handler, args)
+}
+
+// execCommand 检查命令访问的键类型后执行命令
+// 调用者必须持有 commandLock，EXEC 执行排队的命令时已经持有
+func execCommand(ctx *ConnectionContext, commandName string, handler CommandHandler, args []string) (resp.Reply, error) {
+	if err := keyspace.CheckCommand(commandName, args[1:]); err != nil {
+		return nil, err
+	}
+	return handler.Handle(ctx, args[1:])
+}
+
+// writeReply 按连接协商的协议版本编码回复并写给客户端
+func writeReply(conn net.Conn, ctx *ConnectionContext, reply resp.Reply) {
+	conn.Write(reply.AppendRESP(nil, ctx.Protocol))
+}
+
+// commandQueue 缓存读取到但尚未执行的命令
+// 命令阻塞时读取协程仍会继续读取，以便及时发现连接断开
+// 队列中命令的总字节数与单个命令一样受 ClientQueryBufferLimit 限制
+type commandQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []queuedCommand
+	size   int64 // 队列中命令在连接上占用的总字节数
+	closed bool
+	err    error // 读取协程遇到的协议错误，命令执行完后回复给客户端
+}
+
+// queuedCommand 是队列中的一条命令及其在连接上占用的字节数
+type queuedCommand struct {
+	args []string
+	size int64
+}
+
+func newCommandQueue() *commandQueue {
+	q := &commandQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push 把命令放入队列，size 是命令在连接上占用的字节数
+// 队列超过 ClientQueryBufferLimit 时丢弃所有未执行的命令并关闭队列，返回 ErrQueryBufferLimit
+func (q *commandQueue) push(args []string, size int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size+size > resp.ClientQueryBufferLimit {
+		q.items, q.size = nil, 0
+		q.closed = true
+		q.cond.Signal()
+		return resp.ErrQueryBufferLimit
+	}
+	q.items = append(q.items, queuedCommand{args: args, size: size})
+	q.size += size
+	q.cond.Signal()
+	return nil
+}
+
+func (q *commandQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Signal()
+}
+
+// fail 记录协议错误并关闭队列，已读到的命令仍会先执行
+func (q *commandQueue) fail(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.err = err
+	q.closed = true
+	q.cond.Signal()
+}
+
+// pop 取出下一条命令，队列关闭且已取空时返回 false
+func (q *commandQueue) pop() ([]string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	cmd := q.items[0]
+	q.items = q.items[1:]
+	q.size -= cmd.size
+	return cmd.args, true
+}
+
+// readCommands 持续读取连接上的命令放入队列
+// 连接断开时取消连接上下文，使阻塞中的命令立即返回
+func readCommands(conn net.Conn, connCtx *ConnectionContext, queue *commandQueue) {
+	defer queue.close()
+	reader := resp.NewRESPReader(conn)
+	for {
+		args, err := reader.ReadCommand()
+		if err != nil {
+			var protoErr *resp.ProtocolError
+			switch {
+			case err == io.EOF:
+				fmt.Println("Connection closed")
+			case errors.As(err, &protoErr):
+				fmt.Println("Protocol error from client", connCtx.Addr+":", protoErr.Reason)
+				queue.fail(err)
+			default:
+				fmt.Println("Error parsing RESP: ", err.Error())
+			}
+			connCtx.Close()
+			return
+		}
+		if err := queue.push(args, reader.CommandSize()); err != nil {
+			// 与 Redis 一样不回复，直接关闭连接
+			fmt.Println("Closing client", connCtx.Addr+":", err.Error())
+			connCtx.Close()
+			return
 		}
 	}
 }
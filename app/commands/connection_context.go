@@ -1,14 +1,139 @@
 package commands
 
+import (
+	"context"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 // ConnectionContext 保存某个连接的事务状态
 type ConnectionContext struct {
 	InTransaction  bool       // 是否在 MULTI 事务模式中
 	QueuedCommands [][]string // 已排队的命令（后面 EXEC 会用到）
+	TxAborted      bool       // 排队时出现过错误，EXEC 将放弃整个事务
+
+	ID        int64     // 客户端 ID（CLIENT ID）
+	Addr      string    // 客户端地址
+	CreatedAt time.Time // 连接建立时间
+	Protocol  int       // HELLO 协商的协议版本，2 或 3
+
+	ctx    context.Context    // 连接生命周期，断开时取消
+	cancel context.CancelFunc // 取消连接上下文
+
+	propagate [][]string // 替代当前命令向副本传播的命令
+	rewritten bool       // 当前命令是否调用过 PropagateAs
+
+	mu      sync.Mutex
+	name    string                  // CLIENT SETNAME 设置的名称
+	unblock context.CancelCauseFunc // 当前阻塞命令的取消函数，未阻塞时为 nil
 }
 
+var nextClientID atomic.Int64
+
 func NewConnectionContext() *ConnectionContext {
-	return &ConnectionContext{
+	ctx, cancel := context.WithCancel(context.Background())
+	connCtx := &ConnectionContext{
 		InTransaction:  false,
 		QueuedCommands: make([][]string, 0),
+		ID:             nextClientID.Add(1),
+		CreatedAt:      time.Now(),
+		Protocol:       2,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	registerClient(connCtx)
+	return connCtx
+}
+
+// Context 返回连接的上下文，连接断开后被取消
+func (c *ConnectionContext) Context() context.Context {
+	return c.ctx
+}
+
+// Close 取消连接上下文（唤醒所有阻塞操作）并注销客户端
+func (c *ConnectionContext) Close() {
+	c.cancel()
+	unregisterClient(c)
+}
+
+// RESP3 判断客户端是否通过 HELLO 切换到了 RESP3
+func (c *ConnectionContext) RESP3() bool {
+	return c.Protocol == 3
+}
+
+// Name 返回客户端名称
+func (c *ConnectionContext) Name() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.name
+}
+
+// SetName 设置客户端名称
+func (c *ConnectionContext) SetName(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.name = name
+}
+
+// BeginBlocking 为阻塞命令创建可被 CLIENT UNBLOCK 取消的上下文，阻塞等待期间释放命令锁
+// 事务中的阻塞命令不阻塞，返回的上下文已按超时取消，也不释放命令锁
+// 返回的函数必须在阻塞操作结束后调用
+func (c *ConnectionContext) BeginBlocking() (context.Context, func()) {
+	cancelCtx, cancel := context.WithCancelCause(c.ctx)
+	ctx := store.WithWaitUnlocker(cancelCtx, &commandLock)
+	if c.InTransaction {
+		ctx = cancelCtx
+		cancel(errUnblockedTimeout)
+	}
+	c.mu.Lock()
+	c.unblock = cancel
+	c.mu.Unlock()
+	return ctx, func() {
+		c.mu.Lock()
+		c.unblock = nil
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// IsBlocked 判断客户端当前是否阻塞在某个命令上
+func (c *ConnectionContext) IsBlocked() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.unblock != nil
+}
+
+// Unblock 以指定原因取消当前阻塞的命令，客户端未阻塞时返回 false
+func (c *ConnectionContext) Unblock(cause error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.unblock == nil {
+		return false
+	}
+	c.unblock(cause)
+	c.unblock = nil
+	return true
+}
+
+// PropagateAs 用给定的命令替代当前命令向副本传播，用于把非确定性的命令改写为确定性的形式
+// 不传参数表示当前命令不需要传播
+func (c *ConnectionContext) PropagateAs(cmds ...[]string) {
+	c.propagate = append(c.propagate, cmds...)
+	c.rewritten = true
+}
+
+// resetPropagation 在执行每条命令前清除上一条命令的改写结果
+func (c *ConnectionContext) resetPropagation() {
+	c.propagate = nil
+	c.rewritten = false
+}
+
+// propagation 返回当前命令需要传播的命令列表
+func (c *ConnectionContext) propagation(args []string) [][]string {
+	if c.rewritten {
+		return c.propagate
 	}
+	return [][]string{args}
 }
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// 与 Redis 措辞一致的错误，客户端库根据错误码决定是否重试
+
+var (
+	errWrongType = &resp.Error{Code: "WRONGTYPE", Message: "Operation against a key holding the wrong kind of value"}
+	errExecAbort = &resp.Error{Code: "EXECABORT", Message: "Transaction discarded because of previous errors."}
+	errReadOnly  = &resp.Error{Code: "READONLY", Message: "You can't write against a read only replica."}
+)
+
+// wrongArgs 返回参数个数错误，name 为命令名，子命令写作 "client|setname"
+func wrongArgs(name string) error {
+	return resp.NewError("ERR", "wrong number of arguments for '%s' command", strings.ToLower(name))
+}
+
+// unknownCommand 返回未知命令错误，附带最多 128 字节的参数
+func unknownCommand(args []string) error {
+	var sb strings.Builder
+	for _, arg := range args[1:] {
+		if sb.Len() >= 128 {
+			break
+		}
+		sb.WriteString("'" + truncate(arg, 128-sb.Len()) + "' ")
+	}
+	return resp.NewError("ERR", "unknown command '%s', with args beginning with: %s", truncate(args[0], 128), sb.String())
+}
+
+// unknownSubcommand 返回未知子命令错误
+func unknownSubcommand(command, subcommand string) error {
+	return resp.NewError("ERR", "unknown subcommand '%s'. Try %s HELP.", truncate(subcommand, 128), strings.ToUpper(command))
+}
+
+// truncate 截取 s 的前 n 个字节
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"time"
+)
+
+const (
+	activeExpireInterval = 100 * time.Millisecond // 主动过期周期（与 Redis 默认 hz 10 相同）
+	activeExpireKeys     = 20                     // 每轮最多检查的键数
+	activeExpireBudget   = 25 * time.Millisecond  // 每个周期回收所用的最长时间（周期的 25%，与 Redis 相同）
+)
+
+func init() {
+	// 过期字段被删除时（无论主动回收还是访问时发现），向副本传播 HDEL
+	hashStore.SetReclaimHandler(func(key string, fields []string) {
+		PropagateWriteCommand(append([]string{"HDEL", key}, fields...))
+	})
+}
+
+// StartActiveExpireCycle 启动后台协程，定期回收已过期的哈希字段
+// 副本不主动回收，等待主节点传播的 HDEL
+func StartActiveExpireCycle() {
+	go func() {
+		ticker := time.NewTicker(activeExpireInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if GetServerRole() != "master" {
+				continue
+			}
+			// 与 Redis 一样，超过 1/4 的被检查键有字段过期时继续回收，但不超过时间预算
+			deadline := time.Now().Add(activeExpireBudget)
+			for time.Now().Before(deadline) {
+				checked, expired := hashStore.ActiveExpire(activeExpireKeys)
+				if expired*4 <= checked {
+					break
+				}
+			}
+		}
+	}()
+}
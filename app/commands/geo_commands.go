@@ -0,0 +1,518 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/geo"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type GeoAddCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewGeoAddCommand(z store.ZSetOps) *GeoAddCommand {
+	return &GeoAddCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *GeoAddCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 4 {
+		return nil, wrongArgs("geoadd")
+	}
+	var opts store.ZAddOptions
+	ch := false
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			opts.NX = true
+		case "XX":
+			opts.XX = true
+		case "CH":
+			ch = true
+		default:
+			break options
+		}
+	}
+	rest := args[i:]
+	if len(rest) == 0 || len(rest)%3 != 0 {
+		return nil, fmt.Errorf("syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... ")
+	}
+	if opts.NX && opts.XX {
+		return nil, fmt.Errorf("XX and NX options at the same time are not compatible")
+	}
+
+	elements := make([]store.ScoreMember, 0, len(rest)/3)
+	for j := 0; j < len(rest); j += 3 {
+		longitude, latitude, err := parseLongLat(rest[j], rest[j+1])
+		if err != nil {
+			return nil, err
+		}
+		score, _ := geo.Encode(longitude, latitude)
+		elements = append(elements, store.ScoreMember{Member: rest[j+2], Score: float64(score)})
+	}
+	result, err := c.zsetOps.Add(args[0], elements, opts)
+	if err != nil {
+		return nil, err
+	}
+	if ch {
+		return resp.Integer(result.Changed), nil
+	}
+	return resp.Integer(result.Added), nil
+}
+
+type GeoPosCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewGeoPosCommand(z store.ZSetOps) *GeoPosCommand {
+	return &GeoPosCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *GeoPosCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 {
+		return nil, wrongArgs("geopos")
+	}
+	scores, found := c.zsetOps.Scores(args[0], args[1:])
+	positions := make(resp.Array, len(scores))
+	for i, score := range scores {
+		if !found[i] {
+			positions[i] = resp.NullArray{}
+			continue
+		}
+		longitude, latitude := geo.Decode(uint64(score))
+		positions[i] = resp.StringArray([]string{formatCoord(longitude), formatCoord(latitude)})
+	}
+	return positions, nil
+}
+
+type GeoDistCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewGeoDistCommand(z store.ZSetOps) *GeoDistCommand {
+	return &GeoDistCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *GeoDistCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 || len(args) > 4 {
+		return nil, wrongArgs("geodist")
+	}
+	conversion := 1.0
+	if len(args) == 4 {
+		var err error
+		if conversion, err = parseGeoUnit(args[3]); err != nil {
+			return nil, err
+		}
+	}
+	scores, found := c.zsetOps.Scores(args[0], args[1:3])
+	if !found[0] || !found[1] {
+		return resp.Null{}, nil
+	}
+	lon1, lat1 := geo.Decode(uint64(scores[0]))
+	lon2, lat2 := geo.Decode(uint64(scores[1]))
+	return resp.BulkString(formatDistance(geo.Distance(lon1, lat1, lon2, lat2) / conversion)), nil
+}
+
+type GeoHashCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewGeoHashCommand(z store.ZSetOps) *GeoHashCommand {
+	return &GeoHashCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *GeoHashCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 {
+		return nil, wrongArgs("geohash")
+	}
+	scores, found := c.zsetOps.Scores(args[0], args[1:])
+	elements := make(resp.Array, len(scores))
+	for i, score := range scores {
+		if found[i] {
+			elements[i] = resp.BulkString(geo.HashString(uint64(score)))
+		} else {
+			elements[i] = resp.Null{}
+		}
+	}
+	return elements, nil
+}
+
+// geoSearchMode 表示搜索中心和区域的指定方式
+type geoSearchMode int
+
+const (
+	geoRadiusByCoord  geoSearchMode = iota // GEORADIUS key longitude latitude radius unit
+	geoRadiusByMember                      // GEORADIUSBYMEMBER key member radius unit
+	geoSearchByOption                      // GEOSEARCH key FROMMEMBER|FROMLONLAT BYRADIUS|BYBOX
+)
+
+// geoPoint 表示一个搜索结果
+type geoPoint struct {
+	member              string
+	score               float64
+	distance            float64 // 到中心的距离（米）
+	longitude, latitude float64
+}
+
+// GeoSearchCommand 实现 GEOSEARCH、GEOSEARCHSTORE 以及旧式的 GEORADIUS 系列命令
+type GeoSearchCommand struct {
+	zsetOps  store.ZSetOps
+	keyspace *Keyspace
+	name     string
+	mode     geoSearchMode
+	store    bool // GEOSEARCHSTORE：第一个参数是目标键
+	noStore  bool // *_RO 变体不支持 STORE/STOREDIST
+}
+
+func NewGeoRadiusCommand(z store.ZSetOps, k *Keyspace) *GeoSearchCommand {
+	return &GeoSearchCommand{zsetOps: z, keyspace: k, name: "GEORADIUS", mode: geoRadiusByCoord}
+}
+
+func NewGeoRadiusROCommand(z store.ZSetOps) *GeoSearchCommand {
+	return &GeoSearchCommand{zsetOps: z, name: "GEORADIUS_RO", mode: geoRadiusByCoord, noStore: true}
+}
+
+func NewGeoRadiusByMemberCommand(z store.ZSetOps, k *Keyspace) *GeoSearchCommand {
+	return &GeoSearchCommand{zsetOps: z, keyspace: k, name: "GEORADIUSBYMEMBER", mode: geoRadiusByMember}
+}
+
+func NewGeoRadiusByMemberROCommand(z store.ZSetOps) *GeoSearchCommand {
+	return &GeoSearchCommand{zsetOps: z, name: "GEORADIUSBYMEMBER_RO", mode: geoRadiusByMember, noStore: true}
+}
+
+func NewGeoSearchCommand(z store.ZSetOps) *GeoSearchCommand {
+	return &GeoSearchCommand{zsetOps: z, name: "GEOSEARCH", mode: geoSearchByOption, noStore: true}
+}
+
+func NewGeoSearchStoreCommand(z store.ZSetOps, k *Keyspace) *GeoSearchCommand {
+	return &GeoSearchCommand{zsetOps: z, keyspace: k, name: "GEOSEARCHSTORE", mode: geoSearchByOption, store: true}
+}
+
+func (c *GeoSearchCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	minArgs := map[geoSearchMode]int{geoRadiusByCoord: 5, geoRadiusByMember: 4, geoSearchByOption: 5}[c.mode]
+	if c.store {
+		minArgs++
+	}
+	if len(args) < minArgs {
+		return nil, wrongArgs(c.name)
+	}
+
+	var storeKey string
+	if c.store {
+		storeKey, args = args[0], args[1:]
+	}
+	key := args[0]
+	exists := c.zsetOps.Exists(key)
+
+	var shape geo.Shape
+	conversion := 1.0
+	i := 1
+	var err error
+	switch c.mode {
+	case geoRadiusByCoord:
+		if shape.Longitude, shape.Latitude, err = parseLongLat(args[1], args[2]); err != nil {
+			return nil, err
+		}
+		if shape.Radius, conversion, err = parseGeoRadius(args[3], args[4]); err != nil {
+			return nil, err
+		}
+		i = 5
+	case geoRadiusByMember:
+		// 源键不存在时不检查成员和半径，只继续解析选项以决定回复的形式
+		if exists {
+			if shape.Longitude, shape.Latitude, err = c.memberPosition(key, args[1]); err != nil {
+				return nil, err
+			}
+			if shape.Radius, conversion, err = parseGeoRadius(args[2], args[3]); err != nil {
+				return nil, err
+			}
+		}
+		i = 4
+	}
+
+	var withDist, withHash, withCoord, any, storeDist bool
+	var fromMember, fromLonLat, byRadius, byBox bool
+	sortOrder, count := 0, 0 // sortOrder: 1 升序，-1 降序
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		remaining := len(args) - i - 1
+		search := c.mode == geoSearchByOption
+		switch {
+		case opt == "WITHDIST":
+			withDist = true
+		case opt == "WITHHASH":
+			withHash = true
+		case opt == "WITHCOORD":
+			withCoord = true
+		case opt == "ANY":
+			any = true
+		case opt == "ASC":
+			sortOrder = 1
+		case opt == "DESC":
+			sortOrder = -1
+		case opt == "COUNT" && remaining > 0:
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			if n <= 0 {
+				return nil, fmt.Errorf("COUNT must be > 0")
+			}
+			count = int(n)
+			i++
+		case (opt == "STORE" || opt == "STOREDIST") && remaining > 0 && !c.noStore && !search:
+			storeKey, storeDist = args[i+1], opt == "STOREDIST"
+			i++
+		case opt == "STOREDIST" && search && c.store:
+			storeDist = true
+		case opt == "FROMMEMBER" && remaining > 0 && search && !fromMember:
+			// 源键不存在时不查找成员，稍后直接返回空结果
+			if exists {
+				if shape.Longitude, shape.Latitude, err = c.memberPosition(key, args[i+1]); err != nil {
+					return nil, err
+				}
+			}
+			fromMember = true
+			i++
+		case opt == "FROMLONLAT" && remaining > 1 && search && !fromLonLat:
+			if shape.Longitude, shape.Latitude, err = parseLongLat(args[i+1], args[i+2]); err != nil {
+				return nil, err
+			}
+			fromLonLat = true
+			i += 2
+		case opt == "BYRADIUS" && remaining > 1 && search && !byRadius:
+			if shape.Radius, conversion, err = parseGeoRadius(args[i+1], args[i+2]); err != nil {
+				return nil, err
+			}
+			shape.Box, byRadius = false, true
+			i += 2
+		case opt == "BYBOX" && remaining > 2 && search && !byBox:
+			if shape.Width, shape.Height, conversion, err = parseGeoBox(args[i+1], args[i+2], args[i+3]); err != nil {
+				return nil, err
+			}
+			shape.Box, byBox = true, true
+			i += 3
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+
+	if storeKey != "" && (withDist || withHash || withCoord) {
+		what := "STORE option in GEORADIUS"
+		if c.store {
+			what = "GEOSEARCHSTORE"
+		}
+		return nil, fmt.Errorf("%s is not compatible with WITHDIST, WITHHASH and WITHCOORD options", what)
+	}
+	if c.mode == geoSearchByOption && fromMember == fromLonLat {
+		return nil, fmt.Errorf("exactly one of FROMMEMBER or FROMLONLAT can be specified for %s", strings.ToLower(c.name))
+	}
+	if c.mode == geoSearchByOption && byRadius == byBox {
+		return nil, fmt.Errorf("exactly one of BYRADIUS and BYBOX can be specified for %s", strings.ToLower(c.name))
+	}
+	if any && count == 0 {
+		return nil, fmt.Errorf("the ANY argument requires COUNT argument")
+	}
+	// 不带 STORE/STOREDIST 的 GEORADIUS 只读，不需要传播给副本
+	if storeKey == "" {
+		ctx.PropagateAs()
+	}
+
+	// 源键不存在时尽早返回
+	if !exists {
+		if storeKey != "" {
+			c.keyspace.Delete(storeKey)
+			return resp.Integer(0), nil
+		}
+		return resp.Array{}, nil
+	}
+
+	// 指定 COUNT 但未指定顺序且没有 ANY 时，按距离升序返回最近的成员
+	if count > 0 && sortOrder == 0 && !any {
+		sortOrder = 1
+	}
+	limit := 0
+	if any {
+		limit = count
+	}
+	points := c.search(key, shape, limit)
+	switch sortOrder {
+	case 1:
+		sort.SliceStable(points, func(i, j int) bool { return points[i].distance < points[j].distance })
+	case -1:
+		sort.SliceStable(points, func(i, j int) bool { return points[i].distance > points[j].distance })
+	}
+	if count > 0 && len(points) > count {
+		points = points[:count]
+	}
+
+	if storeKey != "" {
+		elements := make([]store.ScoreMember, len(points))
+		for i, p := range points {
+			score := p.score
+			if storeDist {
+				score = p.distance / conversion
+			}
+			elements[i] = store.ScoreMember{Member: p.member, Score: score}
+		}
+		c.keyspace.DeleteOtherTypes(storeKey, "zset")
+		return resp.Integer(c.zsetOps.StoreElements(storeKey, elements)), nil
+	}
+	return encodeGeoPoints(points, conversion, withDist, withHash, withCoord), nil
+}
+
+// memberPosition 返回成员的经纬度
+func (c *GeoSearchCommand) memberPosition(key, member string) (float64, float64, error) {
+	score, ok := c.zsetOps.Score(key, member)
+	if !ok {
+		return 0, 0, fmt.Errorf("could not decode requested zset member")
+	}
+	longitude, latitude := geo.Decode(uint64(score))
+	return longitude, latitude, nil
+}
+
+// search 返回区域内的成员；limit > 0 时（ANY）找到 limit 个即停止
+func (c *GeoSearchCommand) search(key string, shape geo.Shape, limit int) []geoPoint {
+	var points []geoPoint
+	for _, r := range geo.SearchRanges(shape) {
+		if limit > 0 && len(points) >= limit {
+			break
+		}
+		spec := store.RangeSpec{
+			By:    store.ByScore,
+			Score: store.ScoreRange{Min: float64(r.Min), Max: float64(r.Max), MaxEx: true},
+			Count: -1,
+		}
+		for _, e := range c.zsetOps.Range(key, spec) {
+			longitude, latitude := geo.Decode(uint64(e.Score))
+			if distance, ok := shape.Contains(longitude, latitude); ok {
+				points = append(points, geoPoint{
+					member:    e.Member,
+					score:     e.Score,
+					distance:  distance,
+					longitude: longitude,
+					latitude:  latitude,
+				})
+			}
+			if limit > 0 && len(points) >= limit {
+				break
+			}
+		}
+	}
+	return points
+}
+
+// encodeGeoPoints 构建搜索结果，每一项依次为成员、距离、geohash 分数和坐标
+func encodeGeoPoints(points []geoPoint, conversion float64, withDist, withHash, withCoord bool) resp.Reply {
+	if !withDist && !withHash && !withCoord {
+		members := make([]string, len(points))
+		for i, p := range points {
+			members[i] = p.member
+		}
+		return resp.StringArray(members)
+	}
+	results := make(resp.Array, len(points))
+	for i, p := range points {
+		item := resp.Array{resp.BulkString(p.member)}
+		if withDist {
+			item = append(item, resp.BulkString(formatDistance(p.distance/conversion)))
+		}
+		if withHash {
+			item = append(item, resp.Integer(p.score))
+		}
+		if withCoord {
+			item = append(item, resp.StringArray([]string{formatCoord(p.longitude), formatCoord(p.latitude)}))
+		}
+		results[i] = item
+	}
+	return results
+}
+
+// parseLongLat 解析并检查经纬度
+func parseLongLat(lonArg, latArg string) (float64, float64, error) {
+	longitude, ok1 := store.ParseScore(lonArg)
+	latitude, ok2 := store.ParseScore(latArg)
+	if !ok1 || !ok2 {
+		return 0, 0, fmt.Errorf("value is not a valid float")
+	}
+	if longitude < geo.LongMin || longitude > geo.LongMax || latitude < geo.LatMin || latitude > geo.LatMax {
+		return 0, 0, fmt.Errorf("invalid longitude,latitude pair %f,%f", longitude, latitude)
+	}
+	return longitude, latitude, nil
+}
+
+// parseGeoUnit 解析距离单位，返回换算成米的系数
+func parseGeoUnit(unit string) (float64, error) {
+	switch strings.ToLower(unit) {
+	case "m":
+		return 1, nil
+	case "km":
+		return 1000, nil
+	case "ft":
+		return 0.3048, nil
+	case "mi":
+		return 1609.34, nil
+	}
+	return 0, fmt.Errorf("unsupported unit provided. please use M, KM, FT, MI")
+}
+
+// parseGeoRadius 解析半径和单位，返回以米为单位的半径和单位换算系数
+func parseGeoRadius(radiusArg, unitArg string) (float64, float64, error) {
+	radius, ok := store.ParseScore(radiusArg)
+	if !ok {
+		return 0, 0, fmt.Errorf("need numeric radius")
+	}
+	if radius < 0 {
+		return 0, 0, fmt.Errorf("radius cannot be negative")
+	}
+	conversion, err := parseGeoUnit(unitArg)
+	if err != nil {
+		return 0, 0, err
+	}
+	return radius * conversion, conversion, nil
+}
+
+// parseGeoBox 解析矩形的宽、高和单位，返回以米为单位的宽和高以及单位换算系数
+func parseGeoBox(widthArg, heightArg, unitArg string) (float64, float64, float64, error) {
+	width, ok := store.ParseScore(widthArg)
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("need numeric width")
+	}
+	height, ok := store.ParseScore(heightArg)
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("need numeric height")
+	}
+	if width < 0 || height < 0 {
+		return 0, 0, 0, fmt.Errorf("height or width cannot be negative")
+	}
+	conversion, err := parseGeoUnit(unitArg)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return width * conversion, height * conversion, conversion, nil
+}
+
+// formatDistance 按 Redis 的格式输出距离（保留 4 位小数）
+func formatDistance(d float64) string {
+	return strconv.FormatFloat(d, 'f', 4, 64)
+}
+
+// formatCoord 按 Redis 的格式输出坐标：保留 17 位小数并去掉末尾的 0
+func formatCoord(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 17, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,431 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type HSetCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHSetCommand(h store.HashOps) *HSetCommand {
+	return &HSetCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HSetCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return nil, wrongArgs("hset")
+	}
+	added := c.hashOps.SetFields(args[0], args[1:])
+	return resp.Integer(added), nil
+}
+
+// HMSetCommand 处理已废弃的 HMSET 命令，与 HSET 相同但返回 OK
+type HMSetCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHMSetCommand(h store.HashOps) *HMSetCommand {
+	return &HMSetCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HMSetCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return nil, wrongArgs("hmset")
+	}
+	c.hashOps.SetFields(args[0], args[1:])
+	return resp.OK, nil
+}
+
+type HSetNXCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHSetNXCommand(h store.HashOps) *HSetNXCommand {
+	return &HSetNXCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HSetNXCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 {
+		return nil, wrongArgs("hsetnx")
+	}
+	if c.hashOps.SetFieldNX(args[0], args[1], args[2]) {
+		return resp.Integer(1), nil
+	}
+	return resp.Integer(0), nil
+}
+
+type HGetCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHGetCommand(h store.HashOps) *HGetCommand {
+	return &HGetCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HGetCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 2 {
+		return nil, wrongArgs("hget")
+	}
+	value, exists := c.hashOps.GetField(args[0], args[1])
+	if !exists {
+		return resp.Null{}, nil
+	}
+	return resp.BulkString(value), nil
+}
+
+type HMGetCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHMGetCommand(h store.HashOps) *HMGetCommand {
+	return &HMGetCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HMGetCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("hmget")
+	}
+	values, found := c.hashOps.GetFields(args[0], args[1:])
+	respArray := make(resp.Array, len(values))
+	for i, value := range values {
+		if found[i] {
+			respArray[i] = resp.BulkString(value)
+		} else {
+			respArray[i] = resp.Null{}
+		}
+	}
+	return respArray, nil
+}
+
+type HDelCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHDelCommand(h store.HashOps) *HDelCommand {
+	return &HDelCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HDelCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("hdel")
+	}
+	deleted := c.hashOps.DeleteFields(args[0], args[1:])
+	return resp.Integer(deleted), nil
+}
+
+type HLenCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHLenCommand(h store.HashOps) *HLenCommand {
+	return &HLenCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HLenCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 1 {
+		return nil, wrongArgs("hlen")
+	}
+	return resp.Integer(c.hashOps.Length(args[0])), nil
+}
+
+type HStrLenCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHStrLenCommand(h store.HashOps) *HStrLenCommand {
+	return &HStrLenCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HStrLenCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 2 {
+		return nil, wrongArgs("hstrlen")
+	}
+	value, _ := c.hashOps.GetField(args[0], args[1])
+	return resp.Integer(len(value)), nil
+}
+
+type HExistsCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHExistsCommand(h store.HashOps) *HExistsCommand {
+	return &HExistsCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HExistsCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 2 {
+		return nil, wrongArgs("hexists")
+	}
+	if c.hashOps.FieldExists(args[0], args[1]) {
+		return resp.Integer(1), nil
+	}
+	return resp.Integer(0), nil
+}
+
+// hashGetAllMode 决定 HKEYS/HVALS/HGETALL 返回哪些部分
+type hashGetAllMode int
+
+const (
+	hashKeys hashGetAllMode = iota
+	hashVals
+	hashKeysAndVals
+)
+
+// HGetAllCommand 同时实现 HKEYS、HVALS 和 HGETALL
+type HGetAllCommand struct {
+	hashOps store.HashOps
+	name    string
+	mode    hashGetAllMode
+}
+
+func NewHGetAllCommand(h store.HashOps) *HGetAllCommand {
+	return &HGetAllCommand{hashOps: h, name: "HGETALL", mode: hashKeysAndVals}
+}
+
+func NewHKeysCommand(h store.HashOps) *HGetAllCommand {
+	return &HGetAllCommand{hashOps: h, name: "HKEYS", mode: hashKeys}
+}
+
+func NewHValsCommand(h store.HashOps) *HGetAllCommand {
+	return &HGetAllCommand{hashOps: h, name: "HVALS", mode: hashVals}
+}
+
+func (c *HGetAllCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 1 {
+		return nil, wrongArgs(c.name)
+	}
+	pairs := c.hashOps.GetAll(args[0])
+	if c.mode == hashKeysAndVals {
+		return resp.StringMap(pairs), nil
+	}
+	result := make([]string, 0, len(pairs)/2)
+	for i := int(c.mode); i < len(pairs); i += 2 {
+		result = append(result, pairs[i])
+	}
+	return resp.StringArray(result), nil
+}
+
+type HIncrByCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHIncrByCommand(h store.HashOps) *HIncrByCommand {
+	return &HIncrByCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HIncrByCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 {
+		return nil, wrongArgs("hincrby")
+	}
+	delta, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	value, err := c.hashOps.IncrBy(args[0], args[1], delta)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(value), nil
+}
+
+type HIncrByFloatCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHIncrByFloatCommand(h store.HashOps) *HIncrByFloatCommand {
+	return &HIncrByFloatCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HIncrByFloatCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 {
+		return nil, wrongArgs("hincrbyfloat")
+	}
+	delta, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return nil, fmt.Errorf("value is not a valid float")
+	}
+	value, err := c.hashOps.IncrByFloat(args[0], args[1], delta)
+	if err != nil {
+		return nil, err
+	}
+	return resp.BulkString(store.FormatFloat(value)), nil
+}
+
+type HRandFieldCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHRandFieldCommand(h store.HashOps) *HRandFieldCommand {
+	return &HRandFieldCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HRandFieldCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nil, wrongArgs("hrandfield")
+	}
+	key := args[0]
+	// 不带 count：返回单个字段或 null
+	if len(args) == 1 {
+		pairs := c.hashOps.RandomFields(key, 1, false)
+		if len(pairs) == 0 {
+			return resp.Null{}, nil
+		}
+		return resp.BulkString(pairs[0]), nil
+	}
+	count, allowRepeats, err := parseRandomCount(args[1])
+	if err != nil {
+		return nil, err
+	}
+	withValues := false
+	if len(args) == 3 {
+		if strings.ToUpper(args[2]) != "WITHVALUES" {
+			return nil, fmt.Errorf("syntax error")
+		}
+		withValues = true
+	}
+	pairs := c.hashOps.RandomFields(key, count, allowRepeats)
+	if withValues {
+		return encodeStringPairs(ctx, pairs), nil
+	}
+	fields := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		fields = append(fields, pairs[i])
+	}
+	return resp.StringArray(fields), nil
+}
+
+// parseRandomCount 解析 HRANDFIELD、SRANDMEMBER、ZRANDMEMBER 的 count，
+// 负数表示允许重复；与 Redis 一样限制在 ±LONG_MAX/2 以内，避免取反和成对回复时溢出，
+// 允许重复时还要求不超过 store.MaxRandomRepeats，重复的回复必须整个放在内存中
+func parseRandomCount(s string) (int, bool, error) {
+	count, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false, fmt.Errorf("value is not an integer or out of range")
+	}
+	if count < -math.MaxInt64/2 || count > math.MaxInt64/2 {
+		return 0, false, fmt.Errorf("value is out of range")
+	}
+	if count < 0 {
+		if -count > store.MaxRandomRepeats {
+			return 0, false, fmt.Errorf("value is out of range")
+		}
+		return -count, true, nil
+	}
+	return count, false, nil
+}
+
+type HScanCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHScanCommand(h store.HashOps) *HScanCommand {
+	return &HScanCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HScanCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("hscan")
+	}
+	opts, err := parseScanArgs(args[1:], true)
+	if err != nil {
+		return nil, err
+	}
+	pairs, next := c.hashOps.Scan(args[0], opts.cursor, opts.match, opts.count)
+	items := pairs
+	if opts.noValues {
+		items = make([]string, 0, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			items = append(items, pairs[i])
+		}
+	}
+	return encodeScanReply(next, items), nil
+}
+
+// scanOptions 保存 *SCAN 命令的公共参数
+type scanOptions struct {
+	cursor   uint64
+	match    string
+	count    int
+	noValues bool
+}
+
+// parseScanArgs 解析 cursor [MATCH pattern] [COUNT count] [NOVALUES]
+func parseScanArgs(args []string, allowNoValues bool) (scanOptions, error) {
+	opts := scanOptions{count: 10}
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return opts, fmt.Errorf("invalid cursor")
+	}
+	opts.cursor = cursor
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("syntax error")
+			}
+			opts.match = args[i+1]
+			if opts.match == "*" {
+				opts.match = ""
+			}
+			i++
+		case "COUNT":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("syntax error")
+			}
+			count, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return opts, fmt.Errorf("value is not an integer or out of range")
+			}
+			if count < 1 {
+				return opts, fmt.Errorf("syntax error")
+			}
+			opts.count = count
+			i++
+		case "NOVALUES":
+			if !allowNoValues {
+				return opts, fmt.Errorf("syntax error")
+			}
+			opts.noValues = true
+		default:
+			return opts, fmt.Errorf("syntax error")
+		}
+	}
+	return opts, nil
+}
+
+// encodeScanReply 编码 *SCAN 的回复：[next-cursor, [elements...]]
+func encodeScanReply(next uint64, items []string) resp.Reply {
+	return resp.Array{resp.BulkString(strconv.FormatUint(next, 10)), resp.StringArray(items)}
+}
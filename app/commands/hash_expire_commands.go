@@ -0,0 +1,414 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxFieldExpireMs 字段过期时间的上限（与 Redis 相同，2^48-1 毫秒）
+const maxFieldExpireMs = 1<<48 - 1
+
+// parseFieldsArg 解析 FIELDS numfields field [field ...]，perField 为每个字段占用的参数个数
+func parseFieldsArg(args []string, perField int) ([]string, error) {
+	if len(args) < 2 || strings.ToUpper(args[0]) != "FIELDS" {
+		return nil, fmt.Errorf("Mandatory argument FIELDS is missing or not at the right position")
+	}
+	numFields, err := strconv.Atoi(args[1])
+	if err != nil || numFields <= 0 {
+		return nil, fmt.Errorf("Number of fields must be a positive integer")
+	}
+	if len(args)-2 != numFields*perField {
+		return nil, fmt.Errorf("The `numfields` parameter must match the number of arguments")
+	}
+	return args[2:], nil
+}
+
+// parseExpireAt 将 EX/PX/EXAT/PXAT 的参数转换为 Unix 毫秒时间
+func parseExpireAt(cmdName, unit, value string) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value is not an integer or out of range")
+	}
+	invalid := fmt.Errorf("invalid expire time in '%s' command", strings.ToLower(cmdName))
+	if n < 0 || n > maxFieldExpireMs {
+		return 0, invalid
+	}
+	var atMs int64
+	switch unit {
+	case "EX":
+		atMs = time.Now().UnixMilli() + n*1000
+	case "PX":
+		atMs = time.Now().UnixMilli() + n
+	case "EXAT":
+		atMs = n * 1000
+	case "PXAT":
+		atMs = n
+	}
+	if atMs > maxFieldExpireMs {
+		return 0, invalid
+	}
+	return atMs, nil
+}
+
+// fieldsCommand 构造 "<cmd> key [opts...] FIELDS n fields..." 形式的传播命令
+func fieldsCommand(cmd, key string, opts []string, fields []string, numFields int) []string {
+	result := append([]string{cmd, key}, opts...)
+	result = append(result, "FIELDS", strconv.Itoa(numFields))
+	return append(result, fields...)
+}
+
+// HExpireCommand 实现 HEXPIRE、HPEXPIRE、HEXPIREAT 和 HPEXPIREAT
+type HExpireCommand struct {
+	hashOps store.HashOps
+	name    string
+	unit    string // EX/PX/EXAT/PXAT
+}
+
+func NewHExpireCommand(h store.HashOps) *HExpireCommand {
+	return &HExpireCommand{hashOps: h, name: "HEXPIRE", unit: "EX"}
+}
+
+func NewHPExpireCommand(h store.HashOps) *HExpireCommand {
+	return &HExpireCommand{hashOps: h, name: "HPEXPIRE", unit: "PX"}
+}
+
+func NewHExpireAtCommand(h store.HashOps) *HExpireCommand {
+	return &HExpireCommand{hashOps: h, name: "HEXPIREAT", unit: "EXAT"}
+}
+
+func NewHPExpireAtCommand(h store.HashOps) *HExpireCommand {
+	return &HExpireCommand{hashOps: h, name: "HPEXPIREAT", unit: "PXAT"}
+}
+
+func (c *HExpireCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 5 {
+		return nil, wrongArgs(c.name)
+	}
+	key := args[0]
+	expireAtMs, err := parseExpireAt(c.name, c.unit, args[1])
+	if err != nil {
+		return nil, err
+	}
+	rest := args[2:]
+	cond := store.ExpireAlways
+	switch strings.ToUpper(rest[0]) {
+	case "NX":
+		cond = store.ExpireNX
+	case "XX":
+		cond = store.ExpireXX
+	case "GT":
+		cond = store.ExpireGT
+	case "LT":
+		cond = store.ExpireLT
+	}
+	if cond != store.ExpireAlways {
+		rest = rest[1:]
+	}
+	fields, err := parseFieldsArg(rest, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	results := c.hashOps.ExpireFields(key, fields, expireAtMs, cond)
+
+	// 以绝对时间传播，已删除的字段传播为 HDEL
+	var updated, deleted []string
+	for i, r := range results {
+		switch r {
+		case store.FieldExpireSet:
+			updated = append(updated, fields[i])
+		case store.FieldExpiredNow:
+			deleted = append(deleted, fields[i])
+		}
+	}
+	ctx.PropagateAs()
+	if len(updated) > 0 {
+		at := strconv.FormatInt(expireAtMs, 10)
+		ctx.PropagateAs(fieldsCommand("HPEXPIREAT", key, []string{at}, updated, len(updated)))
+	}
+	if len(deleted) > 0 {
+		ctx.PropagateAs(append([]string{"HDEL", key}, deleted...))
+	}
+	return encodeIntegerArray(results), nil
+}
+
+// HTTLCommand 实现 HTTL、HPTTL、HEXPIRETIME 和 HPEXPIRETIME
+type HTTLCommand struct {
+	hashOps  store.HashOps
+	name     string
+	millis   bool // 以毫秒为单位返回
+	absolute bool // 返回 Unix 时间而不是剩余时间
+}
+
+func NewHTTLCommand(h store.HashOps) *HTTLCommand {
+	return &HTTLCommand{hashOps: h, name: "HTTL"}
+}
+
+func NewHPTTLCommand(h store.HashOps) *HTTLCommand {
+	return &HTTLCommand{hashOps: h, name: "HPTTL", millis: true}
+}
+
+func NewHExpireTimeCommand(h store.HashOps) *HTTLCommand {
+	return &HTTLCommand{hashOps: h, name: "HEXPIRETIME", absolute: true}
+}
+
+func NewHPExpireTimeCommand(h store.HashOps) *HTTLCommand {
+	return &HTTLCommand{hashOps: h, name: "HPEXPIRETIME", millis: true, absolute: true}
+}
+
+func (c *HTTLCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 {
+		return nil, wrongArgs(c.name)
+	}
+	fields, err := parseFieldsArg(args[1:], 1)
+	if err != nil {
+		return nil, err
+	}
+	expireTimes := c.hashOps.FieldExpireTimes(args[0], fields)
+	nowMs := time.Now().UnixMilli()
+	results := make([]int, len(expireTimes))
+	for i, at := range expireTimes {
+		if at < 0 {
+			results[i] = int(at) // -2 或 -1
+			continue
+		}
+		if !c.absolute {
+			at -= nowMs
+			if at < 0 {
+				at = 0
+			}
+		}
+		if !c.millis {
+			if c.absolute {
+				at /= 1000
+			} else {
+				at = (at + 999) / 1000
+			}
+		}
+		results[i] = int(at)
+	}
+	return encodeIntegerArray(results), nil
+}
+
+type HPersistCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHPersistCommand(h store.HashOps) *HPersistCommand {
+	return &HPersistCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HPersistCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 {
+		return nil, wrongArgs("hpersist")
+	}
+	fields, err := parseFieldsArg(args[1:], 1)
+	if err != nil {
+		return nil, err
+	}
+	return encodeIntegerArray(c.hashOps.PersistFields(args[0], fields)), nil
+}
+
+type HGetExCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHGetExCommand(h store.HashOps) *HGetExCommand {
+	return &HGetExCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HGetExCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 {
+		return nil, wrongArgs("hgetex")
+	}
+	key := args[0]
+	rest := args[1:]
+	mode := store.TTLNone
+	var expireAtMs int64
+	switch opt := strings.ToUpper(rest[0]); opt {
+	case "EX", "PX", "EXAT", "PXAT":
+		if len(rest) < 2 {
+			return nil, fmt.Errorf("syntax error")
+		}
+		var err error
+		if expireAtMs, err = parseExpireAt("HGETEX", opt, rest[1]); err != nil {
+			return nil, err
+		}
+		mode = store.TTLSet
+		rest = rest[2:]
+	case "PERSIST":
+		mode = store.TTLPersist
+		rest = rest[1:]
+	}
+	fields, err := parseFieldsArg(rest, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	values, found, deleted := c.hashOps.GetFieldsEx(key, fields, mode, expireAtMs)
+
+	respArray := make(resp.Array, len(values))
+	var existing []string
+	for i, value := range values {
+		if found[i] {
+			respArray[i] = resp.BulkString(value)
+			existing = append(existing, fields[i])
+		} else {
+			respArray[i] = resp.Null{}
+		}
+	}
+	// 只读调用不传播；修改过期时间的调用以确定性的形式传播
+	ctx.PropagateAs()
+	if len(existing) > 0 {
+		switch {
+		case deleted:
+			ctx.PropagateAs(append([]string{"HDEL", key}, existing...))
+		case mode == store.TTLSet:
+			at := strconv.FormatInt(expireAtMs, 10)
+			ctx.PropagateAs(fieldsCommand("HPEXPIREAT", key, []string{at}, existing, len(existing)))
+		case mode == store.TTLPersist:
+			ctx.PropagateAs(fieldsCommand("HPERSIST", key, nil, existing, len(existing)))
+		}
+	}
+	return respArray, nil
+}
+
+type HSetExCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHSetExCommand(h store.HashOps) *HSetExCommand {
+	return &HSetExCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HSetExCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 4 {
+		return nil, wrongArgs("hsetex")
+	}
+	key := args[0]
+	rest := args[1:]
+	cond := store.SetAlways
+	mode := store.TTLClear
+	var expireAtMs int64
+	condSet, ttlSet := false, false
+	for len(rest) > 0 && strings.ToUpper(rest[0]) != "FIELDS" {
+		switch opt := strings.ToUpper(rest[0]); opt {
+		case "FNX", "FXX":
+			if condSet {
+				return nil, fmt.Errorf("Only one of FXX or FNX arguments can be specified")
+			}
+			condSet = true
+			cond = store.SetFNX
+			if opt == "FXX" {
+				cond = store.SetFXX
+			}
+			rest = rest[1:]
+		case "EX", "PX", "EXAT", "PXAT":
+			if ttlSet {
+				return nil, fmt.Errorf("Only one of EX, PX, EXAT, PXAT or KEEPTTL arguments can be specified")
+			}
+			if len(rest) < 2 {
+				return nil, fmt.Errorf("syntax error")
+			}
+			var err error
+			if expireAtMs, err = parseExpireAt("HSETEX", opt, rest[1]); err != nil {
+				return nil, err
+			}
+			ttlSet = true
+			mode = store.TTLSet
+			rest = rest[2:]
+		case "KEEPTTL":
+			if ttlSet {
+				return nil, fmt.Errorf("Only one of EX, PX, EXAT, PXAT or KEEPTTL arguments can be specified")
+			}
+			ttlSet = true
+			mode = store.TTLKeep
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	pairs, err := parseFieldsArg(rest, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.hashOps.SetFieldsEx(key, pairs, cond, mode, expireAtMs) {
+		ctx.PropagateAs()
+		return resp.Integer(0), nil
+	}
+
+	// FNX/FXX 已在主节点判断过，传播时去掉；相对时间改写为绝对时间
+	fields := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		fields = append(fields, pairs[i])
+	}
+	switch {
+	case mode == store.TTLSet && expireAtMs <= time.Now().UnixMilli():
+		ctx.PropagateAs(append([]string{"HDEL", key}, fields...))
+	case mode == store.TTLSet:
+		at := strconv.FormatInt(expireAtMs, 10)
+		ctx.PropagateAs(fieldsCommand("HSETEX", key, []string{"PXAT", at}, pairs, len(fields)))
+	case mode == store.TTLKeep:
+		ctx.PropagateAs(fieldsCommand("HSETEX", key, []string{"KEEPTTL"}, pairs, len(fields)))
+	default:
+		ctx.PropagateAs(fieldsCommand("HSETEX", key, nil, pairs, len(fields)))
+	}
+	return resp.Integer(1), nil
+}
+
+type HGetDelCommand struct {
+	hashOps store.HashOps
+}
+
+func NewHGetDelCommand(h store.HashOps) *HGetDelCommand {
+	return &HGetDelCommand{
+		hashOps: h,
+	}
+}
+
+func (c *HGetDelCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 {
+		return nil, wrongArgs("hgetdel")
+	}
+	key := args[0]
+	fields, err := parseFieldsArg(args[1:], 1)
+	if err != nil {
+		return nil, err
+	}
+	values, found := c.hashOps.GetDelFields(key, fields)
+	respArray := make(resp.Array, len(values))
+	deleted := []string{}
+	for i, value := range values {
+		if found[i] {
+			respArray[i] = resp.BulkString(value)
+			deleted = append(deleted, fields[i])
+		} else {
+			respArray[i] = resp.Null{}
+		}
+	}
+	ctx.PropagateAs()
+	if len(deleted) > 0 {
+		ctx.PropagateAs(append([]string{"HDEL", key}, deleted...))
+	}
+	return respArray, nil
+}
+
+// encodeIntegerArray 构建由整数组成的数组
+func encodeIntegerArray(values []int) resp.Array {
+	result := make(resp.Array, len(values))
+	for i, v := range values {
+		result[i] = resp.Integer(v)
+	}
+	return result
+}
@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"strconv"
+	"strings"
+)
+
+// typedStore 是某一种数据类型的存储及其在 TYPE 命令中的名称
+type typedStore struct {
+	typeName string
+	ops      store.KeyOps
+}
+
+// Keyspace 聚合所有类型的存储，提供与类型无关的键操作
+type Keyspace struct {
+	stores []typedStore
+}
+
+func NewKeyspace(stores ...typedStore) *Keyspace {
+	return &Keyspace{
+		stores: stores,
+	}
+}
+
+// Type 返回键的类型名称，不存在时返回 "none"
+func (k *Keyspace) Type(key string) string {
+	for _, s := range k.stores {
+		if s.ops.Exists(key) {
+			return s.typeName
+		}
+	}
+	return "none"
+}
+
+// Exists 检查任意类型的键是否存在
+func (k *Keyspace) Exists(key string) bool {
+	return k.Type(key) != "none"
+}
+
+// Delete 删除任意类型的键，返回键是否存在
+func (k *Keyspace) Delete(key string) bool {
+	deleted := false
+	for _, s := range k.stores {
+		if s.ops.Delete(key) {
+			deleted = true
+		}
+	}
+	return deleted
+}
+
+// DeleteOtherTypes 删除除 typeName 以外其他类型的同名键，用于覆盖写入任意类型的目标键
+func (k *Keyspace) DeleteOtherTypes(key, typeName string) {
+	for _, s := range k.stores {
+		if s.typeName != typeName {
+			s.ops.Delete(key)
+		}
+	}
+}
+
+// CheckType 检查键不存在或者是 types 中的一种，否则返回 WRONGTYPE 错误
+func (k *Keyspace) CheckType(key string, types ...string) error {
+	keyType := k.Type(key)
+	if keyType == "none" {
+		return nil
+	}
+	for _, t := range types {
+		if keyType == t {
+			return nil
+		}
+	}
+	return errWrongType
+}
+
+// CheckCommand 在命令执行前检查它读写的已有键的类型，与 Redis 一样对类型不符的键返回 WRONGTYPE
+// 会被整体覆盖的目标键（如 SINTERSTORE 的 destination）不在检查之列
+func (k *Keyspace) CheckCommand(name string, args []string) error {
+	spec, ok := commandKeyTypes[name]
+	if !ok {
+		return nil
+	}
+	for _, key := range spec.keys(args) {
+		if err := k.CheckType(key, spec.types...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyTypeSpec 描述命令访问的键及这些键允许的类型
+type keyTypeSpec struct {
+	types []string                     // 允许的类型
+	keys  func(args []string) []string // 从参数（不含命令名）中取出要检查的键
+}
+
+// commandKeyTypes 列出需要检查键类型的命令
+var commandKeyTypes = map[string]keyTypeSpec{}
+
+// registerKeyTypes 为一组命令登记相同的键类型规则
+func registerKeyTypes(keys func(args []string) []string, typeName string, names ...string) {
+	for _, name := range names {
+		commandKeyTypes[name] = keyTypeSpec{types: []string{typeName}, keys: keys}
+	}
+}
+
+func init() {
+	registerKeyTypes(firstKey, "string", "GET", "INCR")
+
+	registerKeyTypes(firstKey, "list", "RPUSH", "LPUSH", "LRANGE", "LLEN", "LPOP")
+	registerKeyTypes(allButLast, "list", "BLPOP")
+
+	registerKeyTypes(firstKey, "stream",
+		"XADD", "XRANGE", "XREVRANGE", "XLEN", "XDEL", "XTRIM", "XSETID",
+		"XACK", "XPENDING", "XCLAIM", "XAUTOCLAIM")
+	registerKeyTypes(keysBetween(1, 2), "stream", "XGROUP", "XINFO")
+	registerKeyTypes(streamsKeys(0), "stream", "XREAD")
+	registerKeyTypes(streamsKeys(3), "stream", "XREADGROUP")
+
+	registerKeyTypes(firstKey, "hash",
+		"HSET", "HMSET", "HSETNX", "HGET", "HMGET", "HDEL", "HLEN", "HSTRLEN", "HEXISTS",
+		"HKEYS", "HVALS", "HGETALL", "HINCRBY", "HINCRBYFLOAT", "HRANDFIELD", "HSCAN",
+		"HEXPIRE", "HPEXPIRE", "HEXPIREAT", "HPEXPIREAT", "HTTL", "HPTTL",
+		"HEXPIRETIME", "HPEXPIRETIME", "HPERSIST", "HGETEX", "HSETEX", "HGETDEL")
+
+	registerKeyTypes(firstKey, "set",
+		"SADD", "SREM", "SMEMBERS", "SISMEMBER", "SMISMEMBER", "SCARD", "SPOP", "SRANDMEMBER", "SSCAN")
+	registerKeyTypes(keysBetween(0, 2), "set", "SMOVE")
+	registerKeyTypes(keysBetween(0, -1), "set", "SINTER", "SUNION", "SDIFF")
+	registerKeyTypes(keysBetween(1, -1), "set", "SINTERSTORE", "SUNIONSTORE", "SDIFFSTORE")
+	registerKeyTypes(numKeysAt(0), "set", "SINTERCARD")
+
+	registerKeyTypes(firstKey, "zset",
+		"ZADD", "ZINCRBY", "ZSCORE", "ZMSCORE", "ZRANK", "ZREVRANK", "ZREM", "ZCARD", "ZCOUNT", "ZSCAN",
+		"ZRANGE", "ZREVRANGE", "ZRANGEBYSCORE", "ZREVRANGEBYSCORE", "ZRANGEBYLEX", "ZREVRANGEBYLEX",
+		"ZLEXCOUNT", "ZREMRANGEBYRANK", "ZREMRANGEBYSCORE", "ZREMRANGEBYLEX", "ZPOPMIN", "ZPOPMAX", "ZRANDMEMBER")
+	registerKeyTypes(keysBetween(1, 2), "zset", "ZRANGESTORE")
+	registerKeyTypes(allButLast, "zset", "BZPOPMIN", "BZPOPMAX")
+	registerKeyTypes(numKeysAt(0), "zset", "ZMPOP")
+	registerKeyTypes(numKeysAt(1), "zset", "BZMPOP")
+	// 有序集合的集合运算也接受普通集合作为输入
+	for _, name := range []string{"ZUNION", "ZINTER", "ZDIFF", "ZINTERCARD"} {
+		commandKeyTypes[name] = keyTypeSpec{types: []string{"zset", "set"}, keys: numKeysAt(0)}
+	}
+	for _, name := range []string{"ZUNIONSTORE", "ZINTERSTORE", "ZDIFFSTORE"} {
+		commandKeyTypes[name] = keyTypeSpec{types: []string{"zset", "set"}, keys: numKeysAt(1)}
+	}
+
+	registerKeyTypes(firstKey, "zset",
+		"GEOADD", "GEOPOS", "GEODIST", "GEOHASH", "GEOSEARCH",
+		"GEORADIUS", "GEORADIUS_RO", "GEORADIUSBYMEMBER", "GEORADIUSBYMEMBER_RO")
+	registerKeyTypes(keysBetween(1, 2), "zset", "GEOSEARCHSTORE")
+}
+
+// firstKey 取第一个参数作为键
+func firstKey(args []string) []string {
+	return args[:min(len(args), 1)]
+}
+
+// keysBetween 取 args[from:to] 作为键，to 为 -1 表示到最后一个参数
+func keysBetween(from, to int) func(args []string) []string {
+	return func(args []string) []string {
+		end := len(args)
+		if to >= 0 {
+			end = min(end, to)
+		}
+		if from >= end {
+			return nil
+		}
+		return args[from:end]
+	}
+}
+
+// numKeysAt 取 args[i] 指定个数、紧随其后的键；numkeys 无效时由命令自己报错
+func numKeysAt(i int) func(args []string) []string {
+	return func(args []string) []string {
+		if i >= len(args) {
+			return nil
+		}
+		n, err := strconv.Atoi(args[i])
+		if err != nil || n <= 0 || n > len(args)-i-1 {
+			return nil
+		}
+		return args[i+1 : i+1+n]
+	}
+}
+
+// allButLast 取除最后一个参数（阻塞超时）以外的所有参数作为键
+func allButLast(args []string) []string {
+	return args[:max(len(args)-1, 0)]
+}
+
+// streamsKeys 取 XREAD/XREADGROUP 中 STREAMS 之后的键，skip 是 STREAMS 之前不属于选项的参数个数
+func streamsKeys(skip int) func(args []string) []string {
+	return func(args []string) []string {
+		for i := skip; i < len(args); i++ {
+			if strings.ToUpper(args[i]) == "STREAMS" {
+				rest := args[i+1:]
+				if len(rest)%2 != 0 {
+					return nil
+				}
+				return rest[:len(rest)/2]
+			}
+		}
+		return nil
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
 	"github.com/codecrafters-io/redis-starter-go/app/store"
 	"strconv"
-	"time"
 )
 
 type LPushCommand struct {
@@ -18,18 +17,18 @@ func NewLPushCommand(s store.ListOps) *LPushCommand {
 	}
 }
 
-func (c *LPushCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *LPushCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) < 2 {
-		return "", fmt.Errorf("RPUSH command requires at least two arguments")
+		return nil, wrongArgs("lpush")
 	}
 	key := args[0]
 	elements := args[1:]
 
 	length, err := c.listOps.PrependList(key, elements)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return resp.EncodeInteger(length), nil
+	return resp.Integer(length), nil
 }
 
 type RPushCommand struct {
@@ -42,17 +41,17 @@ func NewRPushCommand(s store.ListOps) *RPushCommand {
 	}
 }
 
-func (c *RPushCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *RPushCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) < 2 {
-		return "", fmt.Errorf("RPUSH command requires at least two arguments")
+		return nil, wrongArgs("rpush")
 	}
 	key := args[0]
 	elements := args[1:]
 	length, err := c.listOps.AppendList(key, elements)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return resp.EncodeInteger(length), nil
+	return resp.Integer(length), nil
 }
 
 type LRangeCommand struct {
@@ -65,29 +64,24 @@ func NewLRangeCommand(s store.ListOps) *LRangeCommand {
 	}
 }
 
-func (c *LRangeCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
-	if len(args) < 2 {
-		return "", fmt.Errorf("LRANGE command requires at least two arguments")
+func (c *LRangeCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 {
+		return nil, wrongArgs("lrange")
 	}
 	key := args[0]
 	start, err := strconv.Atoi(args[1])
 	if err != nil {
-		return "", fmt.Errorf("invalid start index: %s", err.Error())
+		return nil, fmt.Errorf("invalid start index: %s", err.Error())
 	}
 	stop, err := strconv.Atoi(args[2])
 	if err != nil {
-		return "", fmt.Errorf("invalid stop index: %s", err.Error())
+		return nil, fmt.Errorf("invalid stop index: %s", err.Error())
 	}
 	elements, err := c.listOps.GetListRange(key, start, stop)
 	if err != nil {
-		return "", err
-	}
-	// 构建 RESP 数组
-	respArray := make([]interface{}, len(elements))
-	for i, elem := range elements {
-		respArray[i] = elem
+		return nil, err
 	}
-	return resp.EncodeArray(respArray), nil
+	return resp.StringArray(elements), nil
 }
 
 type LLenCommand struct {
@@ -100,16 +94,16 @@ func NewLLenCommand(s store.ListOps) *LLenCommand {
 	}
 }
 
-func (c *LLenCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *LLenCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) != 1 {
-		return "", fmt.Errorf("LLEN command requires exactly one argument")
+		return nil, wrongArgs("llen")
 	}
 	key := args[0]
 	length, err := c.listOps.GetListLength(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return resp.EncodeInteger(length), nil
+	return resp.Integer(length), nil
 }
 
 type LPopCommand struct {
@@ -122,9 +116,9 @@ func NewLPopCommand(s store.ListOps) *LPopCommand {
 	}
 }
 
-func (c *LPopCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *LPopCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) < 1 || len(args) > 2 {
-		return "", fmt.Errorf("LPOP command requires one or two arguments")
+		return nil, wrongArgs("lpop")
 	}
 	key := args[0]
 	count := 1
@@ -132,29 +126,25 @@ func (c *LPopCommand) Handle(ctx *ConnectionContext, args []string) (interface{}
 		var err error
 		count, err = strconv.Atoi(args[1])
 		if err != nil {
-			return "", fmt.Errorf("invalid value not an integer or out of range: %s", args[1])
+			return nil, fmt.Errorf("invalid value not an integer or out of range: %s", args[1])
 		}
 		if count < 0 {
-			return "", fmt.Errorf("count must be non-negative, got %d", count)
+			return nil, fmt.Errorf("count must be non-negative, got %d", count)
 		}
 	}
 	elements, ok, err := c.listOps.LPopElement(key, count)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if !ok {
-		return resp.EncodeNull(), nil
+		return resp.Null{}, nil
 	}
 	if count == 1 && len(elements) == 1 {
 		// 单元素返回批量字符串
-		return resp.EncodeBulkString(elements[0]), nil
+		return resp.BulkString(elements[0]), nil
 	}
 	// 多元素或 count>1 返回数组
-	respArray := make([]interface{}, len(elements))
-	for i, elem := range elements {
-		respArray[i] = elem
-	}
-	return resp.EncodeArray(respArray), nil
+	return resp.StringArray(elements), nil
 }
 
 type BLPopCommand struct {
@@ -167,24 +157,28 @@ func NewBLPopCommand(s store.ListOps) *BLPopCommand {
 	}
 }
 
-func (c *BLPopCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
-	if len(args) != 2 {
-		return "", fmt.Errorf("BLPOP command requires two arguments")
+func (c *BLPopCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("blpop")
 	}
-	key := args[0]
-	timeoutSec, err := strconv.ParseFloat(args[1], 64)
+	keys := args[:len(args)-1]
+	timeout, err := parseBlockTimeout(args[len(args)-1])
 	if err != nil {
-		return "", fmt.Errorf("invalid value timeout: %q", args[1])
+		return nil, err
 	}
-	timeout := time.Duration(timeoutSec * float64(time.Second))
-	element, ok, err := c.listOps.BLPopElement(key, timeout)
+	blockCtx, done := ctx.BeginBlocking()
+	defer done()
+	key, element, ok, err := c.listOps.BLPopElement(blockCtx, keys, timeout)
+	timedOut, err := blockingError(err)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if !ok {
-		return resp.EncodeNull(), nil
+
+	// 副本上不能阻塞，改写为 LPOP 传播；超时则不传播
+	ctx.PropagateAs()
+	if timedOut || !ok {
+		return resp.Null{}, nil
 	}
-	// 构建 RESP 数组 [key, element]
-	respArray := []interface{}{key, element}
-	return resp.EncodeArray(respArray), nil
+	ctx.PropagateAs([]string{"LPOP", key})
+	return resp.StringArray([]string{key, element}), nil
 }
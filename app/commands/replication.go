@@ -100,35 +100,43 @@ func (h *ReplicaHandShaker) ConnectToMaster() {
 	defer masterConn.Close()
 
 	fmt.Println("Connected to master at", masterAddr)
+	// 整个复制连接共用一个 reader，避免缓冲区中的数据在握手步骤之间丢失
+	reader := resp.NewRESPReader(masterConn)
 	// 执行握手步骤
-	if err := h.sendCmdAndRead(masterConn, "PING"); err != nil {
+	if err := h.sendCmdAndRead(masterConn, reader, "PING"); err != nil {
 		fmt.Printf("PING failed: %v", err)
 		return
 	}
-	if err := h.sendCmdAndRead(masterConn, "REPLCONF", "listening-port", fmt.Sprintf("%d", h.ReplicaPort)); err != nil {
+	if err := h.sendCmdAndRead(masterConn, reader, "REPLCONF", "listening-port", fmt.Sprintf("%d", h.ReplicaPort)); err != nil {
 		fmt.Printf("REPLCONF listening-port failed: %v", err)
 		return
 	}
-	if err := h.sendCmdAndRead(masterConn, "REPLCONF", "capa", "psync2"); err != nil {
+	if err := h.sendCmdAndRead(masterConn, reader, "REPLCONF", "capa", "psync2"); err != nil {
 		fmt.Printf("REPLCONF capa failed: %v", err)
 		return
 	}
-	if err := h.sendCmdAndRead(masterConn, "PSYNC", "?", "-1"); err != nil {
+	if err := h.sendCmdAndRead(masterConn, reader, "PSYNC", "?", "-1"); err != nil {
 		fmt.Printf("PSYNC failed: %v", err)
 		return
 	}
+	// FULLRESYNC 之后主节点发送 RDB 快照
+	if _, err := reader.ReadRDB(); err != nil {
+		fmt.Printf("Reading RDB failed: %v", err)
+		return
+	}
 
 	// 握手完成后，处理主节点传播的命令
-	if err := h.handlePropagatedCommands(masterConn); err != nil {
+	if err := h.handlePropagatedCommands(masterConn, reader); err != nil {
 		fmt.Printf("handlePropagatedCommands failed: %v", err)
 		return
 	}
 }
 
 // handlePropagatedCommands 处理主节点传播的命令
-func (h *ReplicaHandShaker) handlePropagatedCommands(conn net.Conn) error {
-	reader := resp.NewRESPReader(conn)
+func (h *ReplicaHandShaker) handlePropagatedCommands(conn net.Conn, reader *resp.RESPReader) error {
 	connCtx := NewConnectionContext()
+	connCtx.Addr = conn.RemoteAddr().String()
+	defer connCtx.Close()
 
 	for {
 		args, err := reader.ReadCommand()
@@ -158,16 +166,14 @@ func (h *ReplicaHandShaker) handlePropagatedCommands(conn net.Conn) error {
 		}
 
 		// 执行命令
-		response, err := handler.Handle(connCtx, args[1:])
+		response, err := runCommand(connCtx, commandName, handler, args)
 		if err != nil {
 			fmt.Printf("Error processing propagated command %s: %v\n", commandName, err)
 			continue
 		}
 		// 仅对 REPLCONF GETACK 发送响应
 		if commandName == "REPLCONF" && len(args) >= 2 && strings.ToUpper(args[1]) == "GETACK" {
-			if respStr, ok := response.(string); ok {
-				conn.Write([]byte(respStr))
-			}
+			writeReply(conn, connCtx, response)
 		}
 		// 其他命令（如 SET、PING）不发送响应
 	}
@@ -184,20 +190,14 @@ func (h *ReplicaHandShaker) connectWithRetry(addr string, maxRetries int, delay
 	return nil, fmt.Errorf("could not connect after %d attempts", maxRetries)
 }
 
-func (h *ReplicaHandShaker) sendCmdAndRead(conn net.Conn, cmdName string, args ...string) error {
-	parts := make([]interface{}, 0, len(args)+1)
-	parts = append(parts, cmdName)
-	for _, a := range args {
-		parts = append(parts, a)
-	}
-	cmd := resp.EncodeArray(parts)
+func (h *ReplicaHandShaker) sendCmdAndRead(conn net.Conn, reader *resp.RESPReader, cmdName string, args ...string) error {
+	cmd := resp.Encode(resp.StringArray(append([]string{cmdName}, args...)), 2)
 
 	_, err := conn.Write([]byte(cmd))
 	if err != nil {
 		return err
 	}
 	// 读取主节点回复
-	reader := resp.NewRESPReader(conn)
 	_, err = reader.Read()
 	if err != nil {
 		return err
@@ -231,11 +231,7 @@ func PropagateWriteCommand(fullArgs []string) {
 		return
 	}
 	// 编码为 RESP 数组
-	parts := make([]interface{}, len(fullArgs))
-	for i, arg := range fullArgs {
-		parts[i] = arg
-	}
-	encodedCmd := resp.EncodeArray(parts)
+	encodedCmd := resp.Encode(resp.StringArray(fullArgs), 2)
 
 	replicaMu.Lock()
 	conns := make([]net.Conn, len(replicaConns))
@@ -1,30 +1,29 @@
 package commands
 
 import (
-	"fmt"
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
 	"strings"
 )
 
 type ReplconfCommand struct{}
 
-func (c *ReplconfCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *ReplconfCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) < 1 {
-		return nil, fmt.Errorf("REPLCONF requires at least one argument")
+		return nil, wrongArgs("replconf")
 	}
 	if strings.ToUpper(args[0]) == "GETACK" {
-		return resp.EncodeArray([]interface{}{"REPLCONF", "ACK", "0"}), nil
+		return resp.StringArray([]string{"REPLCONF", "ACK", "0"}), nil
 	}
-	return resp.EncodeSimpleString("OK"), nil
+	return resp.OK, nil
 }
 
 type PsyncCommand struct {
 }
 
-func (c *PsyncCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *PsyncCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	msg := "FULLRESYNC " + GetMasterReplID() + " 0"
 	return &RDBResponse{
-		Message: resp.EncodeSimpleString(msg),
+		Message: resp.SimpleString(msg),
 		RDBData: GetEmptyRDBData(),
 	}, nil
 }
@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// 回复的类型由连接按协议版本编码；结构在 RESP2 和 RESP3 下不同的回复，由命令根据 ctx.RESP3() 选择
+
+// encodeStringPairs 构建交替排列的字段和值，RESP3 下每对是一个两元素数组，RESP2 下为扁平数组
+func encodeStringPairs(ctx *ConnectionContext, pairs []string) resp.Reply {
+	if !ctx.RESP3() {
+		return resp.StringArray(pairs)
+	}
+	elements := make(resp.Array, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		elements = append(elements, resp.StringArray(pairs[i:i+2]))
+	}
+	return elements
+}
+
+// encodeScoreMember 构建成员及其分数组成的两元素数组
+func encodeScoreMember(e store.ScoreMember) resp.Array {
+	return resp.Array{resp.BulkString(e.Member), resp.Double(e.Score)}
+}
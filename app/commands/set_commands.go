@@ -0,0 +1,361 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"strconv"
+	"strings"
+)
+
+type SAddCommand struct {
+	setOps store.SetOps
+}
+
+func NewSAddCommand(s store.SetOps) *SAddCommand {
+	return &SAddCommand{
+		setOps: s,
+	}
+}
+
+func (c *SAddCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("sadd")
+	}
+	return resp.Integer(c.setOps.Add(args[0], args[1:])), nil
+}
+
+type SRemCommand struct {
+	setOps store.SetOps
+}
+
+func NewSRemCommand(s store.SetOps) *SRemCommand {
+	return &SRemCommand{
+		setOps: s,
+	}
+}
+
+func (c *SRemCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("srem")
+	}
+	return resp.Integer(c.setOps.Remove(args[0], args[1:])), nil
+}
+
+type SMembersCommand struct {
+	setOps store.SetOps
+}
+
+func NewSMembersCommand(s store.SetOps) *SMembersCommand {
+	return &SMembersCommand{
+		setOps: s,
+	}
+}
+
+func (c *SMembersCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 1 {
+		return nil, wrongArgs("smembers")
+	}
+	return resp.StringSet(c.setOps.Members(args[0])), nil
+}
+
+type SIsMemberCommand struct {
+	setOps store.SetOps
+}
+
+func NewSIsMemberCommand(s store.SetOps) *SIsMemberCommand {
+	return &SIsMemberCommand{
+		setOps: s,
+	}
+}
+
+func (c *SIsMemberCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 2 {
+		return nil, wrongArgs("sismember")
+	}
+	if c.setOps.IsMember(args[0], args[1]) {
+		return resp.Integer(1), nil
+	}
+	return resp.Integer(0), nil
+}
+
+type SMIsMemberCommand struct {
+	setOps store.SetOps
+}
+
+func NewSMIsMemberCommand(s store.SetOps) *SMIsMemberCommand {
+	return &SMIsMemberCommand{
+		setOps: s,
+	}
+}
+
+func (c *SMIsMemberCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("smismember")
+	}
+	exists := c.setOps.MembersExist(args[0], args[1:])
+	results := make([]int, len(exists))
+	for i, ok := range exists {
+		if ok {
+			results[i] = 1
+		}
+	}
+	return encodeIntegerArray(results), nil
+}
+
+type SCardCommand struct {
+	setOps store.SetOps
+}
+
+func NewSCardCommand(s store.SetOps) *SCardCommand {
+	return &SCardCommand{
+		setOps: s,
+	}
+}
+
+func (c *SCardCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 1 {
+		return nil, wrongArgs("scard")
+	}
+	return resp.Integer(c.setOps.Card(args[0])), nil
+}
+
+type SPopCommand struct {
+	setOps store.SetOps
+}
+
+func NewSPopCommand(s store.SetOps) *SPopCommand {
+	return &SPopCommand{
+		setOps: s,
+	}
+}
+
+func (c *SPopCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, wrongArgs("spop")
+	}
+	key := args[0]
+	count := 1
+	if len(args) == 2 {
+		var err error
+		count, err = strconv.Atoi(args[1])
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("value is out of range, must be positive")
+		}
+	}
+	popped := c.setOps.Pop(key, count)
+
+	// 随机弹出的结果在副本上无法重现，改写为 SREM 传播
+	ctx.PropagateAs()
+	if len(popped) > 0 {
+		ctx.PropagateAs(append([]string{"SREM", key}, popped...))
+	}
+
+	if len(args) == 1 {
+		if len(popped) == 0 {
+			return resp.Null{}, nil
+		}
+		return resp.BulkString(popped[0]), nil
+	}
+	return resp.StringSet(popped), nil
+}
+
+type SRandMemberCommand struct {
+	setOps store.SetOps
+}
+
+func NewSRandMemberCommand(s store.SetOps) *SRandMemberCommand {
+	return &SRandMemberCommand{
+		setOps: s,
+	}
+}
+
+func (c *SRandMemberCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, wrongArgs("srandmember")
+	}
+	key := args[0]
+	// 不带 count：返回单个元素或 null
+	if len(args) == 1 {
+		members := c.setOps.RandomMembers(key, 1, false)
+		if len(members) == 0 {
+			return resp.Null{}, nil
+		}
+		return resp.BulkString(members[0]), nil
+	}
+	count, allowRepeats, err := parseRandomCount(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return resp.StringArray(c.setOps.RandomMembers(key, count, allowRepeats)), nil
+}
+
+type SMoveCommand struct {
+	setOps store.SetOps
+}
+
+func NewSMoveCommand(s store.SetOps) *SMoveCommand {
+	return &SMoveCommand{
+		setOps: s,
+	}
+}
+
+func (c *SMoveCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 {
+		return nil, wrongArgs("smove")
+	}
+	if c.setOps.Move(args[0], args[1], args[2]) {
+		return resp.Integer(1), nil
+	}
+	return resp.Integer(0), nil
+}
+
+type SScanCommand struct {
+	setOps store.SetOps
+}
+
+func NewSScanCommand(s store.SetOps) *SScanCommand {
+	return &SScanCommand{
+		setOps: s,
+	}
+}
+
+func (c *SScanCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("sscan")
+	}
+	opts, err := parseScanArgs(args[1:], false)
+	if err != nil {
+		return nil, err
+	}
+	members, next := c.setOps.Scan(args[0], opts.cursor, opts.match, opts.count)
+	return encodeScanReply(next, members), nil
+}
+
+// setOperation 表示一种多集合运算
+type setOperation int
+
+const (
+	setInter setOperation = iota
+	setUnion
+	setDiff
+)
+
+// apply 对给定的键执行集合运算
+func (op setOperation) apply(setOps store.SetOps, keys []string) []string {
+	switch op {
+	case setInter:
+		return setOps.Inter(keys)
+	case setUnion:
+		return setOps.Union(keys)
+	default:
+		return setOps.Diff(keys)
+	}
+}
+
+// SetAlgebraCommand 实现 SINTER、SUNION 和 SDIFF
+type SetAlgebraCommand struct {
+	setOps store.SetOps
+	name   string
+	op     setOperation
+}
+
+func NewSInterCommand(s store.SetOps) *SetAlgebraCommand {
+	return &SetAlgebraCommand{setOps: s, name: "SINTER", op: setInter}
+}
+
+func NewSUnionCommand(s store.SetOps) *SetAlgebraCommand {
+	return &SetAlgebraCommand{setOps: s, name: "SUNION", op: setUnion}
+}
+
+func NewSDiffCommand(s store.SetOps) *SetAlgebraCommand {
+	return &SetAlgebraCommand{setOps: s, name: "SDIFF", op: setDiff}
+}
+
+func (c *SetAlgebraCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 {
+		return nil, wrongArgs(c.name)
+	}
+	return resp.StringSet(c.op.apply(c.setOps, args)), nil
+}
+
+// SetAlgebraStoreCommand 实现 SINTERSTORE、SUNIONSTORE 和 SDIFFSTORE
+// 结果覆盖任意类型的目标键，结果为空时删除目标键
+type SetAlgebraStoreCommand struct {
+	setOps   store.SetOps
+	keyspace *Keyspace
+	name     string
+	op       setOperation
+}
+
+func NewSInterStoreCommand(s store.SetOps, k *Keyspace) *SetAlgebraStoreCommand {
+	return &SetAlgebraStoreCommand{setOps: s, keyspace: k, name: "SINTERSTORE", op: setInter}
+}
+
+func NewSUnionStoreCommand(s store.SetOps, k *Keyspace) *SetAlgebraStoreCommand {
+	return &SetAlgebraStoreCommand{setOps: s, keyspace: k, name: "SUNIONSTORE", op: setUnion}
+}
+
+func NewSDiffStoreCommand(s store.SetOps, k *Keyspace) *SetAlgebraStoreCommand {
+	return &SetAlgebraStoreCommand{setOps: s, keyspace: k, name: "SDIFFSTORE", op: setDiff}
+}
+
+func (c *SetAlgebraStoreCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs(c.name)
+	}
+	dst := args[0]
+	members := c.op.apply(c.setOps, args[1:])
+	c.keyspace.DeleteOtherTypes(dst, "set")
+	return resp.Integer(c.setOps.StoreMembers(dst, members)), nil
+}
+
+type SInterCardCommand struct {
+	setOps store.SetOps
+}
+
+func NewSInterCardCommand(s store.SetOps) *SInterCardCommand {
+	return &SInterCardCommand{
+		setOps: s,
+	}
+}
+
+func (c *SInterCardCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("sintercard")
+	}
+	keys, rest, err := parseNumKeys(args)
+	if err != nil {
+		return nil, err
+	}
+	limit := 0
+	for i := 0; i < len(rest); i++ {
+		if strings.ToUpper(rest[i]) != "LIMIT" || i+1 >= len(rest) {
+			return nil, fmt.Errorf("syntax error")
+		}
+		limit, err = strconv.Atoi(rest[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		if limit < 0 {
+			return nil, fmt.Errorf("LIMIT can't be negative")
+		}
+		i++
+	}
+	return resp.Integer(c.setOps.InterCard(keys, limit)), nil
+}
+
+// parseNumKeys 解析 numkeys key [key ...] 形式的参数，返回键列表和剩余参数
+func parseNumKeys(args []string) ([]string, []string, error) {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	if numKeys <= 0 {
+		return nil, nil, fmt.Errorf("numkeys should be greater than 0")
+	}
+	if numKeys > len(args)-1 {
+		return nil, nil, fmt.Errorf("Number of keys can't be greater than number of args")
+	}
+	return args[1 : 1+numKeys], args[1+numKeys:], nil
+}
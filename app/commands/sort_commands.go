@@ -0,0 +1,242 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortCommand 实现 SORT 和只读的 SORT_RO，支持列表、集合和有序集合
+type SortCommand struct {
+	keyspace  *Keyspace
+	listOps   store.ListOps
+	setOps    store.SetOps
+	zsetOps   store.ZSetOps
+	stringOps store.StringOps
+	hashOps   store.HashOps
+	name      string
+	readOnly  bool // SORT_RO 不支持 STORE，可以在副本上执行
+}
+
+func NewSortCommand(k *Keyspace, l store.ListOps, s store.SetOps, z store.ZSetOps, str store.StringOps, h store.HashOps) *SortCommand {
+	return &SortCommand{keyspace: k, listOps: l, setOps: s, zsetOps: z, stringOps: str, hashOps: h, name: "SORT"}
+}
+
+func NewSortROCommand(k *Keyspace, l store.ListOps, s store.SetOps, z store.ZSetOps, str store.StringOps, h store.HashOps) *SortCommand {
+	return &SortCommand{keyspace: k, listOps: l, setOps: s, zsetOps: z, stringOps: str, hashOps: h, name: "SORT_RO", readOnly: true}
+}
+
+// sortItem 是参与排序的元素及其排序依据
+type sortItem struct {
+	value  string
+	score  float64 // 数值排序时的权重
+	cmp    string  // ALPHA 且使用 BY 时的比较对象
+	hasCmp bool
+}
+
+func (c *SortCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 {
+		return nil, wrongArgs(c.name)
+	}
+	key := args[0]
+	var byPattern, storeKey string
+	var getPatterns []string
+	desc, alpha, dontSort := false, false, false
+	offset, count := 0, -1
+	for i := 1; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		remaining := len(args) - i - 1
+		switch {
+		case opt == "ASC":
+			desc = false
+		case opt == "DESC":
+			desc = true
+		case opt == "ALPHA":
+			alpha = true
+		case opt == "LIMIT" && remaining >= 2:
+			var err1, err2 error
+			offset, err1 = strconv.Atoi(args[i+1])
+			count, err2 = strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			i += 2
+		case opt == "STORE" && remaining >= 1 && !c.readOnly:
+			storeKey = args[i+1]
+			i++
+		case opt == "BY" && remaining >= 1:
+			byPattern = args[i+1]
+			// 不含 "*" 的模式（例如 BY nosort）表示不排序
+			dontSort = !strings.Contains(byPattern, "*")
+			i++
+		case opt == "GET" && remaining >= 1:
+			getPatterns = append(getPatterns, args[i+1])
+			i++
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	// 不带 STORE 的 SORT 只读，不需要传播给副本
+	if storeKey == "" {
+		ctx.PropagateAs()
+	}
+
+	var elements []string
+	keyType := c.keyspace.Type(key)
+	switch keyType {
+	case "none":
+	case "list":
+		elements, _ = c.listOps.GetListRange(key, 0, -1)
+	case "set":
+		elements = c.setOps.Members(key)
+	case "zset":
+		for _, e := range c.zsetOps.Range(key, store.RangeSpec{By: store.ByRank, Start: 0, Stop: -1}) {
+			elements = append(elements, e.Member)
+		}
+	default:
+		return nil, errWrongType
+	}
+	// 集合没有顺序，保存结果时仍按元素排序，保证副本上的结果一致
+	if dontSort && keyType == "set" && storeKey != "" {
+		dontSort, alpha, byPattern = false, true, ""
+	}
+
+	items := make([]sortItem, len(elements))
+	for i, e := range elements {
+		items[i] = sortItem{value: e}
+	}
+	if !dontSort {
+		if err := c.sortItems(items, byPattern, alpha, desc); err != nil {
+			return nil, err
+		}
+	} else if desc && keyType == "zset" {
+		// 不排序时有序集合按分数逆序返回
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	items = limitSortItems(items, offset, count)
+
+	// GET 引用的值不存在时回复 null，STORE 时存为空字符串
+	var values []string
+	var result resp.Array
+	for _, item := range items {
+		if len(getPatterns) == 0 {
+			values = append(values, item.value)
+			result = append(result, resp.BulkString(item.value))
+			continue
+		}
+		for _, pattern := range getPatterns {
+			v, ok := c.lookupByPattern(pattern, item.value)
+			values = append(values, v)
+			if ok {
+				result = append(result, resp.BulkString(v))
+			} else {
+				result = append(result, resp.Null{})
+			}
+		}
+	}
+
+	if storeKey != "" {
+		c.keyspace.DeleteOtherTypes(storeKey, "list")
+		return resp.Integer(c.listOps.StoreList(storeKey, values)), nil
+	}
+	return result, nil
+}
+
+// sortItems 计算排序依据并排序；数值排序时权重相同的元素按元素本身的字典序排列
+func (c *SortCommand) sortItems(items []sortItem, byPattern string, alpha, desc bool) error {
+	for i := range items {
+		var by string
+		var ok bool
+		if byPattern != "" {
+			by, ok = c.lookupByPattern(byPattern, items[i].value)
+		} else {
+			by, ok = items[i].value, true
+		}
+		if alpha {
+			items[i].cmp, items[i].hasCmp = by, ok
+			continue
+		}
+		if ok {
+			score, err := strconv.ParseFloat(by, 64)
+			if err != nil || math.IsNaN(score) {
+				return fmt.Errorf("One or more scores can't be converted into double")
+			}
+			items[i].score = score
+		}
+	}
+
+	compare := func(a, b sortItem) int {
+		switch {
+		case !alpha:
+			if a.score != b.score {
+				if a.score < b.score {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(a.value, b.value)
+		case byPattern == "":
+			return strings.Compare(a.value, b.value)
+		case !a.hasCmp || !b.hasCmp:
+			// 查找不到比较对象的元素排在前面
+			if a.hasCmp == b.hasCmp {
+				return 0
+			}
+			if !a.hasCmp {
+				return -1
+			}
+			return 1
+		default:
+			return strings.Compare(a.cmp, b.cmp)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if desc {
+			return compare(items[i], items[j]) > 0
+		}
+		return compare(items[i], items[j]) < 0
+	})
+	return nil
+}
+
+// limitSortItems 按 LIMIT offset count 截取结果，count 为负数表示不限
+func limitSortItems(items []sortItem, offset, count int) []sortItem {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return nil
+	}
+	end := len(items)
+	if count >= 0 && count < end-offset {
+		end = offset + count
+	}
+	return items[offset:end]
+}
+
+// lookupByPattern 用元素替换模式中的第一个 "*" 并读取对应的值：
+// "#" 表示元素本身，"key*->field" 读取哈希字段，否则读取字符串键
+func (c *SortCommand) lookupByPattern(pattern, subst string) (string, bool) {
+	if pattern == "#" {
+		return subst, true
+	}
+	star := strings.IndexByte(pattern, '*')
+	if star < 0 {
+		return "", false
+	}
+	keyPattern, field := pattern, ""
+	if arrow := strings.Index(pattern[star+1:], "->"); arrow >= 0 && star+1+arrow+2 < len(pattern) {
+		keyPattern, field = pattern[:star+1+arrow], pattern[star+1+arrow+2:]
+	}
+	key := keyPattern[:star] + subst + keyPattern[star+1:]
+	if field != "" {
+		return c.hashOps.GetField(key, field)
+	}
+	return c.stringOps.GetString(key)
+}
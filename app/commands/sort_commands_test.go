@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLimitSortItems(t *testing.T) {
+	items := []sortItem{{value: "3"}, {value: "5"}, {value: "7"}}
+	tests := []struct {
+		offset, count int
+		want          []string
+	}{
+		{0, -1, []string{"3", "5", "7"}},
+		{1, 1, []string{"5"}},
+		{1, 10, []string{"5", "7"}},
+		{-1, 2, []string{"3", "5"}},
+		{3, 1, nil},
+		{1, 0, []string{}},
+		// offset+count 溢出时不能越界
+		{1, math.MaxInt, []string{"5", "7"}},
+		{math.MaxInt, math.MaxInt, nil},
+	}
+	for _, tt := range tests {
+		got := limitSortItems(items, tt.offset, tt.count)
+		if len(got) != len(tt.want) {
+			t.Errorf("limitSortItems(%d, %d) = %v, want %v", tt.offset, tt.count, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].value != tt.want[i] {
+				t.Errorf("limitSortItems(%d, %d) = %v, want %v", tt.offset, tt.count, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -19,68 +19,305 @@ func NewXAddCommand(ss store.StreamOps) *XAddCommand {
 	}
 }
 
-func (c *XAddCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *XAddCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) < 3 {
-		return "", fmt.Errorf("XADD command requires at least three arguments")
+		return nil, wrongArgs("xadd")
 	}
 	streamKey := args[0]
-	entryID := args[1]
+	opts, rest, err := parseStreamTrimArgs(args[1:], true)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < 1 {
+		return nil, wrongArgs("xadd")
+	}
+	entryID := rest[0]
 
 	// 验证键值对参数
-	fieldArgs := args[2:]
-	if len(fieldArgs)%2 != 0 {
-		return "", fmt.Errorf("wrong number of arguments for fields")
+	fieldArgs := rest[1:]
+	if len(fieldArgs) == 0 || len(fieldArgs)%2 != 0 {
+		return nil, wrongArgs("xadd")
+	}
+	// 添加条目到流，字段保持给出的顺序
+	id, err := c.streamOps.AddEntry(streamKey, entryID, fieldArgs, opts)
+	if err != nil {
+		return nil, err
+	}
+	if id.IsZero() {
+		return resp.Null{}, nil
+	}
+	// 自动生成的 ID 在副本上无法重现，改写为实际的 ID 传播
+	if id.String() != entryID {
+		propagated := append([]string{"XADD"}, args[:len(args)-len(rest)]...)
+		propagated = append(propagated, id.String())
+		ctx.PropagateAs(append(propagated, fieldArgs...))
+	}
+	return resp.BulkString(id.String()), nil
+}
+
+// parseStreamTrimArgs 解析 XADD 与 XTRIM 共用的选项：
+// [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold [LIMIT count]]，返回选项和剩余参数
+// allowNoMkStream 为 false 时（XTRIM）不接受 NOMKSTREAM
+func parseStreamTrimArgs(args []string, allowNoMkStream bool) (store.XAddOptions, []string, error) {
+	var opts store.XAddOptions
+	limitSet := false
+	i := 0
+loop:
+	for ; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "NOMKSTREAM" && allowNoMkStream:
+			opts.NoMkStream = true
+		case (option == "MAXLEN" || option == "MINID") && i+1 < len(args):
+			i++
+			if args[i] == "~" || args[i] == "=" {
+				opts.Trim.Approx = args[i] == "~"
+				i++
+			}
+			if i >= len(args) {
+				return opts, nil, fmt.Errorf("syntax error")
+			}
+			if option == "MAXLEN" {
+				maxLen, err := strconv.Atoi(args[i])
+				if err != nil {
+					return opts, nil, fmt.Errorf("value is not an integer or out of range")
+				}
+				if maxLen < 0 {
+					return opts, nil, fmt.Errorf("The MAXLEN argument must be >= 0.")
+				}
+				opts.Trim.Strategy, opts.Trim.MaxLen = store.TrimMaxLen, maxLen
+			} else {
+				minID, err := store.ParseStreamID(args[i])
+				if err != nil {
+					return opts, nil, err
+				}
+				opts.Trim.Strategy, opts.Trim.MinID = store.TrimMinID, minID
+			}
+		case option == "LIMIT" && i+1 < len(args):
+			i++
+			limit, err := strconv.Atoi(args[i])
+			if err != nil {
+				return opts, nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			if limit < 0 {
+				return opts, nil, fmt.Errorf("The LIMIT argument must be >= 0.")
+			}
+			opts.Trim.Limit, limitSet = limit, true
+		default:
+			break loop
+		}
+	}
+	if limitSet && !opts.Trim.Approx {
+		return opts, nil, fmt.Errorf("syntax error, LIMIT cannot be used without the special ~ option")
+	}
+	// 近似裁剪默认限制单次移除的条目数，避免阻塞太久
+	if opts.Trim.Approx && !limitSet {
+		opts.Trim.Limit = store.DefaultTrimLimit
+	}
+	return opts, args[i:], nil
+}
+
+type XLenCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXLenCommand(ss store.StreamOps) *XLenCommand {
+	return &XLenCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XLenCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 1 {
+		return nil, wrongArgs("xlen")
+	}
+	return resp.Integer(c.streamOps.Len(args[0])), nil
+}
+
+type XDelCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXDelCommand(ss store.StreamOps) *XDelCommand {
+	return &XDelCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XDelCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("xdel")
+	}
+	deleted, err := c.streamOps.DeleteEntries(args[0], args[1:])
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(deleted), nil
+}
+
+type XTrimCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXTrimCommand(ss store.StreamOps) *XTrimCommand {
+	return &XTrimCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XTrimCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 {
+		return nil, wrongArgs("xtrim")
+	}
+	opts, rest, err := parseStreamTrimArgs(args[1:], false)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 || opts.Trim.Strategy == store.TrimNone {
+		return nil, fmt.Errorf("syntax error")
 	}
-	// 创建字段映射
-	fields := make(map[string]string)
-	for i := 0; i < len(fieldArgs); i += 2 {
-		fields[fieldArgs[i]] = fieldArgs[i+1]
+	return resp.Integer(c.streamOps.Trim(args[0], opts.Trim)), nil
+}
+
+type XSetIDCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXSetIDCommand(ss store.StreamOps) *XSetIDCommand {
+	return &XSetIDCommand{
+		streamOps: ss,
+	}
+}
+
+// Handle 处理 XSETID key last-id [ENTRIESADDED entries-added] [MAXDELETEDID max-deleted-id]
+func (c *XSetIDCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("xsetid")
 	}
-	// 添加条目到流
-	id, err := c.streamOps.AddEntry(streamKey, entryID, fields)
+	lastID, err := store.ParseStreamID(args[1])
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return resp.EncodeBulkString(id), nil
+	opts := store.SetIDOptions{LastID: lastID, EntriesAdded: -1}
+	for i := 2; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("syntax error")
+		}
+		switch strings.ToUpper(args[i]) {
+		case "ENTRIESADDED":
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			if n < 0 {
+				return nil, fmt.Errorf("entries_added must be positive")
+			}
+			opts.EntriesAdded = n
+		case "MAXDELETEDID":
+			maxDeletedID, err := store.ParseStreamID(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if lastID.Compare(maxDeletedID) < 0 {
+				return nil, fmt.Errorf("The ID specified in XSETID is smaller than the provided max_deleted_entry_id")
+			}
+			opts.MaxDeletedID = &maxDeletedID
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	if err := c.streamOps.SetID(args[0], opts); err != nil {
+		return nil, err
+	}
+	return resp.OK, nil
 }
 
+// XRangeCommand 实现 XRANGE 和 XREVRANGE
 type XRangeCommand struct {
 	streamOps store.StreamOps
+	name      string
+	reverse   bool
 }
 
 func NewXRangeCommand(ss store.StreamOps) *XRangeCommand {
 	return &XRangeCommand{
 		streamOps: ss,
+		name:      "XRANGE",
 	}
 }
 
-func (c *XRangeCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
-	if len(args) != 3 {
-		return "", fmt.Errorf("XADD command requires exactly three arguments")
+func NewXRevRangeCommand(ss store.StreamOps) *XRangeCommand {
+	return &XRangeCommand{
+		streamOps: ss,
+		name:      "XREVRANGE",
+		reverse:   true,
+	}
+}
+
+func (c *XRangeCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 && len(args) != 5 {
+		return nil, wrongArgs(c.name)
 	}
 	streamKey := args[0]
+	// XREVRANGE 的参数顺序为 end start
 	startID, endID := args[1], args[2]
-	entries, err := c.streamOps.GetRange(streamKey, startID, endID)
+	if c.reverse {
+		startID, endID = endID, startID
+	}
+	count := 0
+	if len(args) == 5 {
+		if strings.ToUpper(args[3]) != "COUNT" {
+			return nil, fmt.Errorf("syntax error")
+		}
+		n, err := strconv.Atoi(args[4])
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		// COUNT 0（或负数）返回空数组
+		if n <= 0 {
+			return resp.Array{}, nil
+		}
+		count = n
+	}
+	entries, err := c.streamOps.GetRange(streamKey, startID, endID, count, c.reverse)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	// 构建 RESP 数组
-	respArray := make([]interface{}, 0, len(entries))
+	return encodeStreamEntries(entries), nil
+}
+
+// encodeStreamEntries 构建条目数组，每个条目是 [ID, [field1, value1, ...]]
+// 已被删除的条目（只在读取消费者历史时出现）字段为 null
+func encodeStreamEntries(entries []store.StreamEntry) resp.Array {
+	respArray := make(resp.Array, 0, len(entries))
 	for _, entry := range entries {
-		// 每个条目是一个数组：[ID, [field1, value1, field2, value2, ...]]
-		entryData := make([]interface{}, 2)
-		entryData[0] = entry.ID // 条目 ID
-		// 构建字段数组
-		fields := make([]interface{}, 0, len(entry.Fields)*2)
-		for key, value := range entry.Fields {
-			fields = append(fields, key, value)
+		var fields resp.Reply = resp.Null{}
+		if entry.Fields != nil {
+			fields = resp.StringArray(entry.Fields)
 		}
-		entryData[1] = fields // 字段列表
-		respArray = append(respArray, entryData)
+		respArray = append(respArray, resp.Array{resp.BulkString(entry.ID.String()), fields})
 	}
+	return respArray
+}
 
-	// 编码为 RESP 数组
-	return resp.EncodeArray(respArray), nil
+// encodeStreamsReply 按键的顺序构建 XREAD/XREADGROUP 的结果：[[key, [[id, [fields...]], ...]], ...]
+// RESP3 下为 {key: [[id, [fields...]], ...], ...} 映射。不在 result 中的键被跳过
+func encodeStreamsReply(ctx *ConnectionContext, keys []string, result map[string][]store.StreamEntry) resp.Reply {
+	streams := make(resp.Array, 0, len(result)*2)
+	for _, key := range keys {
+		entries, exists := result[key]
+		if !exists {
+			continue
+		}
+		if ctx.RESP3() {
+			streams = append(streams, resp.BulkString(key), encodeStreamEntries(entries))
+		} else {
+			streams = append(streams, resp.Array{resp.BulkString(key), encodeStreamEntries(entries)})
+		}
+	}
+	if ctx.RESP3() {
+		return resp.Map(streams)
+	}
+	return streams
 }
 
 type XReadCommand struct {
@@ -93,81 +330,74 @@ func NewXReadCommand(ss store.StreamOps) *XReadCommand {
 	}
 }
 
-func (c *XReadCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *XReadCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) < 3 {
-		return "", fmt.Errorf("XREAD command requires at least three arguments")
+		return nil, wrongArgs("xread")
 	}
 
-	// 检查是否包含 BLOCK 选项
+	// 解析 STREAMS 之前的 COUNT 和 BLOCK 选项
+	count := 0
+	block := false
 	var blockTimeout time.Duration
-	var streamsIndex int
-	if strings.ToUpper(args[0]) == "BLOCK" {
-		if len(args) < 5 {
-			return "", fmt.Errorf("XREAD with BLOCK requires at least five arguments")
-		}
-		timeoutMs, err := strconv.ParseInt(args[1], 10, 64)
-		if err != nil {
-			return "", fmt.Errorf("invalid BLOCK timeout: %s", err.Error())
-		}
-		if timeoutMs < 0 {
-			return "", fmt.Errorf("BLOCK timeout must be non-negative")
-		}
-		if strings.ToUpper(args[2]) != "STREAMS" {
-			return "", fmt.Errorf("syntax error: expected STREAMS after BLOCK")
+	i := 0
+	for ; i < len(args) && strings.ToUpper(args[i]) != "STREAMS"; i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COUNT":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("syntax error")
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			count = max(n, 0)
+			i++
+		case "BLOCK":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("syntax error")
+			}
+			timeoutMs, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BLOCK timeout: %s", err.Error())
+			}
+			if timeoutMs < 0 {
+				return nil, fmt.Errorf("BLOCK timeout must be non-negative")
+			}
+			block, blockTimeout = true, time.Duration(timeoutMs)*time.Millisecond
+			i++
+		default:
+			return nil, fmt.Errorf("syntax error")
 		}
-		blockTimeout = time.Duration(timeoutMs) * time.Millisecond
-		streamsIndex = 3
-	} else if strings.ToUpper(args[0]) != "STREAMS" {
-		return "", fmt.Errorf("syntax error")
-	} else {
-		streamsIndex = 1
 	}
 
 	// 分割流键和 ID
-	numStreams := (len(args) - streamsIndex) / 2
-	if len(args)-streamsIndex != numStreams*2 {
-		return "", fmt.Errorf("number of stream keys and IDs must match")
+	streamArgs := args[min(i+1, len(args)):]
+	if i >= len(args) || len(streamArgs) == 0 || len(streamArgs)%2 != 0 {
+		return nil, fmt.Errorf("Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified.")
 	}
-	keys := args[streamsIndex : streamsIndex+numStreams]
-	ids := args[streamsIndex+numStreams:]
+	keys := streamArgs[:len(streamArgs)/2]
+	ids := streamArgs[len(keys):]
 
-	// 调用阻塞或非阻塞查询
+	// 调用阻塞或非阻塞查询，BLOCK 0 表示一直等待
 	var result map[string][]store.StreamEntry
 	var err error
-	if blockTimeout > 0 || streamsIndex == 3 {
-		result, err = c.streamOps.ReadStreamsBlocking(keys, ids, blockTimeout)
+	if block {
+		blockCtx, done := ctx.BeginBlocking()
+		defer done()
+		result, err = c.streamOps.ReadStreamsBlocking(blockCtx, keys, ids, count, blockTimeout)
+		var timedOut bool
+		if timedOut, err = blockingError(err); timedOut {
+			return resp.Null{}, nil
+		}
 	} else {
-		result, err = c.streamOps.ReadStreams(keys, ids)
+		result, err = c.streamOps.ReadStreams(keys, ids, count)
 	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	// 如果没有任何结果，返回nil（RESP null）
 	if result == nil || len(result) == 0 {
-		return resp.EncodeNull(), nil
-	}
-	// 构建 RESP 数组
-	respArray := make([]interface{}, 0, len(result))
-	for _, key := range keys {
-		entries, exists := result[key]
-		if !exists || len(entries) == 0 {
-			continue // 跳过空流
-		}
-		// 构建流的条目数组
-		streamEntries := make([]interface{}, 0, len(entries))
-		for _, entry := range entries {
-			// 构建条目：[ID, [field1, value1, field2, value2, ...]]
-			entryData := []interface{}{entry.ID}
-			fields := make([]interface{}, 0, len(entry.Fields)*2)
-			for field, value := range entry.Fields {
-				fields = append(fields, field, value)
-			}
-			entryData = append(entryData, fields)
-			streamEntries = append(streamEntries, entryData)
-		}
-		// 添加流结果：[key, [[id, [fields...]], ...]]
-		respArray = append(respArray, []interface{}{key, streamEntries})
+		return resp.Null{}, nil
 	}
-	// 编码为RESP数组
-	return resp.EncodeArray(respArray), nil
+	return encodeStreamsReply(ctx, keys, result), nil
 }
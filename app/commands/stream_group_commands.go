@@ -0,0 +1,511 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type XGroupCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXGroupCommand(ss store.StreamOps) *XGroupCommand {
+	return &XGroupCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XGroupCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 {
+		return nil, wrongArgs("xgroup")
+	}
+	subcommand := strings.ToUpper(args[0])
+	switch {
+	case (subcommand == "CREATE" && len(args) >= 4) || (subcommand == "SETID" && len(args) >= 4):
+		key, group, id := args[1], args[2], args[3]
+		mkStream, entriesRead, err := parseXGroupOptions(args[4:], subcommand == "CREATE")
+		if err != nil {
+			return nil, err
+		}
+		if subcommand == "CREATE" {
+			err = c.streamOps.CreateGroup(key, group, id, mkStream, entriesRead)
+		} else {
+			err = c.streamOps.SetGroupID(key, group, id, entriesRead)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return resp.OK, nil
+	case subcommand == "DESTROY" && len(args) == 3:
+		destroyed, err := c.streamOps.DestroyGroup(args[1], args[2])
+		if err != nil {
+			return nil, err
+		}
+		if destroyed {
+			return resp.Integer(1), nil
+		}
+		return resp.Integer(0), nil
+	case subcommand == "CREATECONSUMER" && len(args) == 4:
+		created, err := c.streamOps.CreateConsumer(args[1], args[2], args[3])
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			return resp.Integer(1), nil
+		}
+		return resp.Integer(0), nil
+	case subcommand == "DELCONSUMER" && len(args) == 4:
+		pending, err := c.streamOps.DeleteConsumer(args[1], args[2], args[3])
+		if err != nil {
+			return nil, err
+		}
+		return resp.Integer(pending), nil
+	default:
+		switch subcommand {
+		case "CREATE", "SETID", "DESTROY", "CREATECONSUMER", "DELCONSUMER":
+			return nil, wrongArgs("xgroup|" + args[0])
+		}
+		return nil, unknownSubcommand("XGROUP", args[0])
+	}
+}
+
+// parseXGroupOptions 解析 XGROUP CREATE 的 [MKSTREAM] [ENTRIESREAD n] 和 XGROUP SETID 的 [ENTRIESREAD n]
+func parseXGroupOptions(args []string, allowMkStream bool) (bool, int64, error) {
+	mkStream := false
+	entriesRead := int64(-1)
+	for i := 0; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "MKSTREAM" && allowMkStream:
+			mkStream = true
+		case option == "ENTRIESREAD" && i+1 < len(args):
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return false, 0, fmt.Errorf("value is not an integer or out of range")
+			}
+			if n < -1 {
+				return false, 0, fmt.Errorf("value for ENTRIESREAD must be positive or -1")
+			}
+			entriesRead = n
+			i++
+		default:
+			return false, 0, fmt.Errorf("syntax error")
+		}
+	}
+	return mkStream, entriesRead, nil
+}
+
+type XReadGroupCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXReadGroupCommand(ss store.StreamOps) *XReadGroupCommand {
+	return &XReadGroupCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XReadGroupCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 6 {
+		return nil, wrongArgs("xreadgroup")
+	}
+	if strings.ToUpper(args[0]) != "GROUP" {
+		return nil, fmt.Errorf("syntax error")
+	}
+	group, consumer := args[1], args[2]
+
+	count := 0
+	noAck := false
+	block := false
+	var blockTimeout time.Duration
+	i := 3
+	for ; i < len(args) && strings.ToUpper(args[i]) != "STREAMS"; i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COUNT":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("syntax error")
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			count = max(n, 0)
+			i++
+		case "BLOCK":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("syntax error")
+			}
+			ms, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("timeout is not an integer or out of range")
+			}
+			if ms < 0 {
+				return nil, fmt.Errorf("timeout is negative")
+			}
+			block, blockTimeout = true, time.Duration(ms)*time.Millisecond
+			i++
+		case "NOACK":
+			noAck = true
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	// 分割流键和 ID
+	streamArgs := args[min(i+1, len(args)):]
+	if i >= len(args) || len(streamArgs) == 0 || len(streamArgs)%2 != 0 {
+		return nil, fmt.Errorf("Unbalanced 'xreadgroup' list of streams: for each stream key an ID or '>' must be specified.")
+	}
+	keys := streamArgs[:len(streamArgs)/2]
+	ids := streamArgs[len(keys):]
+	onlyNew := true
+	for _, id := range ids {
+		switch id {
+		case ">":
+		case "$":
+			return nil, fmt.Errorf("The $ ID is meaningless in the context of XREADGROUP: you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set.")
+		default:
+			onlyNew = false
+		}
+	}
+
+	// 读取历史时总有结果，只有全部读取新条目时才阻塞
+	var result map[string][]store.StreamEntry
+	var deliveries map[string]store.GroupDelivery
+	var err error
+	if block && onlyNew {
+		blockCtx, done := ctx.BeginBlocking()
+		defer done()
+		result, deliveries, err = c.streamOps.ReadGroupBlocking(blockCtx, keys, group, consumer, count, noAck, blockTimeout)
+		var timedOut bool
+		if timedOut, err = blockingError(err); timedOut {
+			result, err = nil, nil
+		}
+	} else {
+		result, deliveries, err = c.streamOps.ReadGroup(keys, ids, group, consumer, count, noAck)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 与 Redis 一样，投递的每个条目以带投递时间的 XCLAIM 传播，再以 XGROUP SETID 推进组的位置，
+	// 副本的待确认条目与主节点有相同的投递时间；读取历史没有需要复制的副作用
+	ctx.PropagateAs()
+	for _, key := range keys {
+		delivery, delivered := deliveries[key]
+		if !delivered {
+			continue
+		}
+		claims := store.ClaimResult{LastID: delivery.LastID, EntriesRead: delivery.EntriesRead}
+		if noAck {
+			// NOACK 不产生待确认条目，只需在副本上创建消费者
+			ctx.PropagateAs([]string{"XGROUP", "CREATECONSUMER", key, group, consumer})
+		} else {
+			for _, entry := range result[key] {
+				claims.Claimed = append(claims.Claimed, store.ClaimedEntry{StreamEntry: entry, DeliveryTime: delivery.Time, DeliveryCount: 1})
+			}
+		}
+		propagateClaims(ctx, key, group, consumer, claims)
+	}
+
+	if len(result) == 0 {
+		return resp.NullArray{}, nil
+	}
+	return encodeStreamsReply(ctx, keys, result), nil
+}
+
+type XAckCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXAckCommand(ss store.StreamOps) *XAckCommand {
+	return &XAckCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XAckCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 {
+		return nil, wrongArgs("xack")
+	}
+	acked, err := c.streamOps.Ack(args[0], args[1], args[2:])
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(acked), nil
+}
+
+type XPendingCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXPendingCommand(ss store.StreamOps) *XPendingCommand {
+	return &XPendingCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XPendingCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("xpending")
+	}
+	key, group := args[0], args[1]
+	// 概要形式：[条目数, 最小ID, 最大ID, [[消费者, 条目数], ...]]
+	if len(args) == 2 {
+		summary, err := c.streamOps.PendingSummary(key, group)
+		if err != nil {
+			return nil, err
+		}
+		if summary.Count == 0 {
+			return resp.Array{resp.Integer(0), resp.Null{}, resp.Null{}, resp.NullArray{}}, nil
+		}
+		consumers := make(resp.Array, len(summary.Consumers))
+		for i, consumer := range summary.Consumers {
+			consumers[i] = resp.StringArray([]string{consumer.Name, strconv.Itoa(consumer.Count)})
+		}
+		return resp.Array{
+			resp.Integer(summary.Count),
+			resp.BulkString(summary.MinID.String()),
+			resp.BulkString(summary.MaxID.String()),
+			consumers,
+		}, nil
+	}
+
+	// 扩展形式：[IDLE min-idle-time] start end count [consumer]
+	var opts store.PendingRangeOptions
+	rest := args[2:]
+	if strings.ToUpper(rest[0]) == "IDLE" && len(rest) > 1 {
+		ms, err := strconv.ParseInt(rest[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		opts.MinIdle = time.Duration(ms) * time.Millisecond
+		rest = rest[2:]
+	}
+	if len(rest) < 3 || len(rest) > 4 {
+		return nil, fmt.Errorf("syntax error")
+	}
+	var err error
+	if opts.Start, err = store.ParseRangeID(rest[0], false); err != nil {
+		return nil, err
+	}
+	if opts.End, err = store.ParseRangeID(rest[1], true); err != nil {
+		return nil, err
+	}
+	if opts.Count, err = strconv.Atoi(rest[2]); err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	if len(rest) == 4 {
+		opts.Consumer = rest[3]
+	}
+	pending, err := c.streamOps.PendingRange(key, group, opts)
+	if err != nil {
+		return nil, err
+	}
+	// 每个条目是 [ID, 消费者, 空闲毫秒数, 投递次数]
+	elements := make(resp.Array, len(pending))
+	for i, p := range pending {
+		elements[i] = resp.Array{
+			resp.BulkString(p.ID.String()),
+			resp.BulkString(p.Consumer),
+			resp.Integer(p.Idle.Milliseconds()),
+			resp.Integer(p.DeliveryCount),
+		}
+	}
+	return elements, nil
+}
+
+type XClaimCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXClaimCommand(ss store.StreamOps) *XClaimCommand {
+	return &XClaimCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XClaimCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 5 {
+		return nil, wrongArgs("xclaim")
+	}
+	key, group, consumer := args[0], args[1], args[2]
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid min-idle-time argument for XCLAIM")
+	}
+	opts := store.ClaimOptions{MinIdle: time.Duration(max(minIdle, 0)) * time.Millisecond, RetryCount: -1}
+
+	// ID 一直持续到第一个不是 ID 的参数，之后是选项
+	i := 4
+	ids := []store.StreamID{}
+	for ; i < len(args); i++ {
+		id, err := store.ParseStreamID(args[i])
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, store.ErrInvalidStreamID
+	}
+	now := time.Now()
+	for ; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		switch {
+		case option == "FORCE":
+			opts.Force = true
+		case option == "JUSTID":
+			opts.JustID = true
+		case option == "IDLE" && i+1 < len(args):
+			ms, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid IDLE option argument for XCLAIM")
+			}
+			opts.DeliveryTime = now.Add(-time.Duration(ms) * time.Millisecond)
+			i++
+		case option == "TIME" && i+1 < len(args):
+			ms, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid TIME option argument for XCLAIM")
+			}
+			opts.DeliveryTime = time.UnixMilli(ms)
+			i++
+		case option == "RETRYCOUNT" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("Invalid RETRYCOUNT option argument for XCLAIM")
+			}
+			opts.RetryCount = n
+			i++
+		case option == "LASTID" && i+1 < len(args):
+			if opts.LastID, err = store.ParseStreamID(args[i+1]); err != nil {
+				return nil, err
+			}
+			i++
+		default:
+			return nil, fmt.Errorf("Unrecognized XCLAIM option '%s'", args[i])
+		}
+	}
+	// 投递时间不能晚于当前时间
+	if opts.DeliveryTime.After(now) {
+		opts.DeliveryTime = now
+	}
+
+	result, err := c.streamOps.Claim(key, group, consumer, ids, opts)
+	if err != nil {
+		return nil, err
+	}
+	propagateClaims(ctx, key, group, consumer, result)
+	return encodeClaimed(result.Claimed, opts.JustID), nil
+}
+
+type XAutoClaimCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXAutoClaimCommand(ss store.StreamOps) *XAutoClaimCommand {
+	return &XAutoClaimCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XAutoClaimCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 5 {
+		return nil, wrongArgs("xautoclaim")
+	}
+	key, group, consumer := args[0], args[1], args[2]
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid min-idle-time argument for XAUTOCLAIM")
+	}
+	opts := store.ClaimOptions{MinIdle: time.Duration(max(minIdle, 0)) * time.Millisecond, RetryCount: -1}
+	start, err := store.ParseRangeID(args[4], false)
+	if err != nil {
+		return nil, err
+	}
+	count := 100
+	for i := 5; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "JUSTID":
+			opts.JustID = true
+		case option == "COUNT" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			// 与 Redis 一样限制 COUNT，使最多检查的条目数不会溢出
+			if n < 1 || n > math.MaxInt/store.AutoClaimAttemptsFactor {
+				return nil, fmt.Errorf("COUNT must be > 0")
+			}
+			count = n
+			i++
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+
+	result, err := c.streamOps.AutoClaim(key, group, consumer, start, count, opts)
+	if err != nil {
+		return nil, err
+	}
+	propagateClaims(ctx, key, group, consumer, result)
+	// [下一次扫描的起点, 认领的条目, 已删除的 ID]
+	return resp.Array{
+		resp.BulkString(result.NextID.String()),
+		encodeClaimed(result.Claimed, opts.JustID),
+		resp.StringArray(formatStreamIDs(result.Deleted)),
+	}, nil
+}
+
+// encodeClaimed 构建认领的条目，justID 为 true 时只返回 ID
+func encodeClaimed(claimed []store.ClaimedEntry, justID bool) resp.Array {
+	if justID {
+		ids := make([]string, len(claimed))
+		for i, entry := range claimed {
+			ids[i] = entry.ID.String()
+		}
+		return resp.StringArray(ids)
+	}
+	entries := make([]store.StreamEntry, len(claimed))
+	for i, entry := range claimed {
+		entries[i] = entry.StreamEntry
+	}
+	return encodeStreamEntries(entries)
+}
+
+// propagateClaims 把认领结果改写为确定的命令传播：
+// 每个认领的条目一条带 TIME 和 RETRYCOUNT 的 XCLAIM ... FORCE JUSTID，
+// 被删除的条目用 XACK 移出 PEL，LASTID 的推进用 XGROUP SETID
+func propagateClaims(ctx *ConnectionContext, key, group, consumer string, result store.ClaimResult) {
+	ctx.PropagateAs()
+	for _, entry := range result.Claimed {
+		ctx.PropagateAs([]string{
+			"XCLAIM", key, group, consumer, "0", entry.ID.String(),
+			"TIME", strconv.FormatInt(entry.DeliveryTime.UnixMilli(), 10),
+			"RETRYCOUNT", strconv.Itoa(entry.DeliveryCount),
+			"FORCE", "JUSTID",
+		})
+	}
+	if len(result.Deleted) > 0 {
+		ctx.PropagateAs(append([]string{"XACK", key, group}, formatStreamIDs(result.Deleted)...))
+	}
+	if !result.LastID.IsZero() {
+		ctx.PropagateAs([]string{
+			"XGROUP", "SETID", key, group, result.LastID.String(),
+			"ENTRIESREAD", strconv.FormatInt(result.EntriesRead, 10),
+		})
+	}
+}
+
+// formatStreamIDs 把 ID 转换为 ms-seq 形式的字符串
+func formatStreamIDs(ids []store.StreamID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id.String()
+	}
+	return result
+}
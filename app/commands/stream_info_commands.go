@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"strconv"
+	"strings"
+)
+
+type XInfoCommand struct {
+	streamOps store.StreamOps
+}
+
+func NewXInfoCommand(ss store.StreamOps) *XInfoCommand {
+	return &XInfoCommand{
+		streamOps: ss,
+	}
+}
+
+func (c *XInfoCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 {
+		return nil, wrongArgs("xinfo")
+	}
+	subcommand := strings.ToUpper(args[0])
+	switch {
+	case subcommand == "STREAM" && len(args) >= 2:
+		return c.stream(args[1], args[2:])
+	case subcommand == "GROUPS" && len(args) == 2:
+		groups, err := c.streamOps.GroupsInfo(args[1])
+		if err != nil {
+			return nil, err
+		}
+		elements := make(resp.Array, len(groups))
+		for i, group := range groups {
+			elements[i] = encodeGroupInfo(group, false)
+		}
+		return elements, nil
+	case subcommand == "CONSUMERS" && len(args) == 3:
+		consumers, err := c.streamOps.ConsumersInfo(args[1], args[2])
+		if err != nil {
+			return nil, err
+		}
+		elements := make(resp.Array, len(consumers))
+		for i, consumer := range consumers {
+			// 从未成功读到条目的消费者 inactive 为 -1
+			inactive := -1
+			if !consumer.ActiveTime.IsZero() {
+				inactive = int(consumer.Inactive.Milliseconds())
+			}
+			elements[i] = resp.Map{
+				resp.BulkString("name"), resp.BulkString(consumer.Name),
+				resp.BulkString("pending"), resp.Integer(consumer.Pending),
+				resp.BulkString("idle"), resp.Integer(consumer.Idle.Milliseconds()),
+				resp.BulkString("inactive"), resp.Integer(inactive),
+			}
+		}
+		return elements, nil
+	default:
+		switch subcommand {
+		case "STREAM", "GROUPS", "CONSUMERS":
+			return nil, wrongArgs("xinfo|" + args[0])
+		}
+		return nil, unknownSubcommand("XINFO", args[0])
+	}
+}
+
+// stream 处理 XINFO STREAM key [FULL [COUNT count]]
+func (c *XInfoCommand) stream(key string, args []string) (resp.Reply, error) {
+	full := false
+	count := 10
+	switch {
+	case len(args) == 0:
+	case strings.ToUpper(args[0]) == "FULL" && len(args) == 1:
+		full = true
+	case strings.ToUpper(args[0]) == "FULL" && len(args) == 3 && strings.ToUpper(args[1]) == "COUNT":
+		n, err := strconv.Atoi(args[2])
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		full, count = true, max(n, 0)
+	default:
+		return nil, fmt.Errorf("syntax error")
+	}
+	info, err := c.streamOps.StreamInfo(key, full, count)
+	if err != nil {
+		return nil, err
+	}
+	fields := resp.Map{
+		resp.BulkString("length"), resp.Integer(info.Length),
+		resp.BulkString("last-generated-id"), resp.BulkString(info.LastGeneratedID.String()),
+		resp.BulkString("max-deleted-entry-id"), resp.BulkString(info.MaxDeletedEntryID.String()),
+		resp.BulkString("entries-added"), resp.Integer(info.EntriesAdded),
+		resp.BulkString("recorded-first-entry-id"), resp.BulkString(info.RecordedFirstEntryID.String()),
+	}
+	if !full {
+		fields = append(fields,
+			resp.BulkString("groups"), resp.Integer(info.Groups),
+			resp.BulkString("first-entry"), encodeStreamEntry(info.FirstEntry),
+			resp.BulkString("last-entry"), encodeStreamEntry(info.LastEntry),
+		)
+		return fields, nil
+	}
+	groups := make(resp.Array, len(info.GroupDetails))
+	for i, group := range info.GroupDetails {
+		groups[i] = encodeGroupInfo(group, true)
+	}
+	fields = append(fields,
+		resp.BulkString("entries"), encodeStreamEntries(info.Entries),
+		resp.BulkString("groups"), groups,
+	)
+	return fields, nil
+}
+
+// encodeGroupInfo 构建消费者组的信息，full 为 true 时使用 XINFO STREAM FULL 的格式
+func encodeGroupInfo(group store.GroupInfo, full bool) resp.Reply {
+	var entriesRead, lag resp.Reply = resp.Null{}, resp.Null{}
+	if group.EntriesRead != -1 {
+		entriesRead = resp.Integer(group.EntriesRead)
+	}
+	if group.LagValid {
+		lag = resp.Integer(group.Lag)
+	}
+	if !full {
+		return resp.Map{
+			resp.BulkString("name"), resp.BulkString(group.Name),
+			resp.BulkString("consumers"), resp.Integer(group.Consumers),
+			resp.BulkString("pending"), resp.Integer(group.Pending),
+			resp.BulkString("last-delivered-id"), resp.BulkString(group.LastDeliveredID.String()),
+			resp.BulkString("entries-read"), entriesRead,
+			resp.BulkString("lag"), lag,
+		}
+	}
+
+	// 组的每个待确认条目是 [ID, 消费者, 投递时间, 投递次数]
+	pending := make(resp.Array, len(group.PendingEntries))
+	for i, p := range group.PendingEntries {
+		pending[i] = resp.Array{
+			resp.BulkString(p.ID.String()),
+			resp.BulkString(p.Consumer),
+			resp.Integer(p.DeliveryTime.UnixMilli()),
+			resp.Integer(p.DeliveryCount),
+		}
+	}
+	consumers := make(resp.Array, len(group.ConsumerDetails))
+	for i, consumer := range group.ConsumerDetails {
+		// 消费者的每个待确认条目是 [ID, 投递时间, 投递次数]
+		consumerPending := make(resp.Array, len(consumer.PendingEntries))
+		for j, p := range consumer.PendingEntries {
+			consumerPending[j] = resp.Array{
+				resp.BulkString(p.ID.String()),
+				resp.Integer(p.DeliveryTime.UnixMilli()),
+				resp.Integer(p.DeliveryCount),
+			}
+		}
+		activeTime := -1
+		if !consumer.ActiveTime.IsZero() {
+			activeTime = int(consumer.ActiveTime.UnixMilli())
+		}
+		consumers[i] = resp.Map{
+			resp.BulkString("name"), resp.BulkString(consumer.Name),
+			resp.BulkString("seen-time"), resp.Integer(consumer.SeenTime.UnixMilli()),
+			resp.BulkString("active-time"), resp.Integer(activeTime),
+			resp.BulkString("pel-count"), resp.Integer(consumer.Pending),
+			resp.BulkString("pending"), consumerPending,
+		}
+	}
+	return resp.Map{
+		resp.BulkString("name"), resp.BulkString(group.Name),
+		resp.BulkString("last-delivered-id"), resp.BulkString(group.LastDeliveredID.String()),
+		resp.BulkString("entries-read"), entriesRead,
+		resp.BulkString("lag"), lag,
+		resp.BulkString("pel-count"), resp.Integer(group.Pending),
+		resp.BulkString("pending"), pending,
+		resp.BulkString("consumers"), consumers,
+	}
+}
+
+// encodeStreamEntry 构建单个条目，条目为 nil 时返回 null
+func encodeStreamEntry(entry *store.StreamEntry) resp.Reply {
+	if entry == nil {
+		return resp.Null{}
+	}
+	return encodeStreamEntries([]store.StreamEntry{*entry})[0]
+}
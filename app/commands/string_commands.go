@@ -11,20 +11,22 @@ import (
 
 type SetCommand struct {
 	stringOps store.StringOps
+	keyspace  *Keyspace
 }
 
-func NewSetCommand(s store.StringOps) *SetCommand {
+func NewSetCommand(s store.StringOps, k *Keyspace) *SetCommand {
 	return &SetCommand{
 		stringOps: s,
+		keyspace:  k,
 	}
 }
 
-func (c *SetCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *SetCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) < 2 {
-		return "", fmt.Errorf("SET command requires at least two arguments")
+		return nil, wrongArgs("set")
 	}
 	if len(args) > 4 {
-		return "", fmt.Errorf("SET command supports up to four arguments (key, value, PX, expiry)")
+		return nil, fmt.Errorf("SET command supports up to four arguments (key, value, PX, expiry)")
 	}
 
 	key := args[0]
@@ -34,21 +36,23 @@ func (c *SetCommand) Handle(ctx *ConnectionContext, args []string) (interface{},
 
 	if len(args) == 4 {
 		if strings.ToUpper(args[2]) != "PX" {
-			return "", fmt.Errorf("invalid option: %s, expected PX", args[2])
+			return nil, fmt.Errorf("invalid option: %s, expected PX", args[2])
 		}
 		expiryMs, err := strconv.Atoi(args[3])
 		if err != nil {
-			return "", fmt.Errorf("invalid PX value: %s", err.Error())
+			return nil, fmt.Errorf("invalid PX value: %s", err.Error())
 		}
 		if expiryMs <= 0 {
-			return "", fmt.Errorf("PX value must be positive")
+			return nil, fmt.Errorf("PX value must be positive")
 		}
 		expiresAt = time.Now().Add(time.Duration(expiryMs) * time.Millisecond)
 		hasExpiry = true
 	}
 
+	// SET 覆盖任意类型的同名键
+	c.keyspace.DeleteOtherTypes(key, "string")
 	c.stringOps.SetString(key, value, expiresAt, hasExpiry)
-	return resp.EncodeSimpleString("OK"), nil
+	return resp.OK, nil
 }
 
 type GetCommand struct {
@@ -61,17 +65,17 @@ func NewGetCommand(s store.StringOps) *GetCommand {
 	}
 }
 
-func (c *GetCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *GetCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if len(args) != 1 {
-		return "", fmt.Errorf("GET command requires exactly one argument")
+		return nil, wrongArgs("get")
 	}
 
 	value, exists := c.stringOps.GetString(args[0])
 	if !exists {
-		return resp.EncodeNull(), nil
+		return resp.Null{}, nil
 	}
 
-	return resp.EncodeBulkString(value), nil
+	return resp.BulkString(value), nil
 }
 
 type IncrCommand struct {
@@ -84,16 +88,16 @@ func NewIncrCommand(s store.StringOps) *IncrCommand {
 	}
 }
 
-func (c *IncrCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
-	if len(args) < 1 {
-		return "", fmt.Errorf("INCR command requires at least one argument")
+func (c *IncrCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 1 {
+		return nil, wrongArgs("incr")
 	}
 
 	key := args[0]
 
 	newValue, err := c.stringOps.Increment(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return resp.EncodeInteger(newValue), nil
+	return resp.Integer(newValue), nil
 }
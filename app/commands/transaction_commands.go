@@ -8,73 +8,79 @@ import (
 
 type MultiCommand struct{}
 
-func (c *MultiCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *MultiCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	// 如果已经在事务模式，返回错误
 	if ctx.InTransaction {
-		return "", fmt.Errorf("MULTI calls can not be nested")
+		return nil, fmt.Errorf("MULTI calls can not be nested")
 	}
 	ctx.InTransaction = true
+	ctx.TxAborted = false
 	ctx.QueuedCommands = make([][]string, 0) // 清空事务队列
-	return resp.EncodeSimpleString("OK"), nil
+	return resp.OK, nil
 }
 
 type ExecCommand struct{}
 
-func (c *ExecCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *ExecCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if !ctx.InTransaction {
 		// 实现未进入事务时的错误提示
-		return "", fmt.Errorf("EXEC without MULTI")
+		return nil, fmt.Errorf("EXEC without MULTI")
+	}
+	// 排队时出过错，整个事务作废
+	if ctx.TxAborted {
+		ctx.InTransaction, ctx.TxAborted = false, false
+		ctx.QueuedCommands = nil
+		return nil, errExecAbort
 	}
 	// 执行空事务
 	if len(ctx.QueuedCommands) == 0 {
 		ctx.InTransaction = false
-		return resp.EncodeArray(nil), nil
+		return resp.Array{}, nil
 	}
 
-	results := make([]interface{}, 0, len(ctx.QueuedCommands))
+	results := make(resp.Array, 0, len(ctx.QueuedCommands))
 	for _, cmdArgs := range ctx.QueuedCommands {
 		commandName := strings.ToUpper(cmdArgs[0])
 		handler, exists := Commands[commandName]
 		if !exists {
 			// 不存在的命令 → 返回错误响应
-			results = append(results, resp.EncodeError("unknown command '"+cmdArgs[0]+"'"))
+			results = append(results, resp.ErrorReply(unknownCommand(cmdArgs)))
 			continue
 		}
 
-		respValue, err := handler.Handle(ctx, cmdArgs[1:])
+		ctx.resetPropagation()
+		reply, err := execCommand(ctx, commandName, handler, cmdArgs)
 		if err != nil {
-			results = append(results, resp.EncodeError(err.Error()))
-		} else {
-			switch v := respValue.(type) {
-			case string:
-				results = append(results, v)
-			case *RDBResponse:
-				// 事务中不支持RDB响应
-				results = append(results, resp.EncodeError("RDB response not allowed in transaction"))
-			default:
-				// 处理其他类型
-				results = append(results, resp.EncodeError("unsupported response type"))
-			}
-			// 如果是写命令，传播
-			if err == nil && isWriteCommand(commandName) {
-				PropagateWriteCommand(cmdArgs)
+			results = append(results, resp.ErrorReply(err))
+			continue
+		}
+		if _, ok := reply.(*RDBResponse); ok {
+			// 事务中不支持RDB响应
+			results = append(results, resp.NewError("ERR", "RDB response not allowed in transaction"))
+			continue
+		}
+		results = append(results, reply)
+		// 如果是写命令，传播
+		if isWriteCommand(commandName) {
+			for _, cmd := range ctx.propagation(cmdArgs) {
+				PropagateWriteCommand(cmd)
 			}
 		}
 	}
 	ctx.InTransaction = false
 	ctx.QueuedCommands = nil
 
-	return resp.EncodeArrayRaw(results), nil
+	return results, nil
 }
 
 type DiscardCommand struct{}
 
-func (c *DiscardCommand) Handle(ctx *ConnectionContext, args []string) (interface{}, error) {
+func (c *DiscardCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
 	if !ctx.InTransaction {
-		return "", fmt.Errorf("DISCARD without MULTI")
+		return nil, fmt.Errorf("DISCARD without MULTI")
 	}
 	// 丢弃队列，退出事务
 	ctx.QueuedCommands = nil
-	ctx.InTransaction = false
-	return resp.EncodeSimpleString("OK"), nil
+	ctx.InTransaction, ctx.TxAborted = false, false
+	return resp.OK, nil
 }
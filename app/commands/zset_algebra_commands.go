@@ -0,0 +1,309 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// zsetAggregate 表示 AGGREGATE 选项，决定同一成员在多个输入中的分数如何合并
+type zsetAggregate int
+
+const (
+	aggregateSum zsetAggregate = iota
+	aggregateMin
+	aggregateMax
+)
+
+func (a zsetAggregate) apply(acc, score float64) float64 {
+	switch a {
+	case aggregateMin:
+		return math.Min(acc, score)
+	case aggregateMax:
+		return math.Max(acc, score)
+	}
+	// +inf 与 -inf 相加得到 NaN，与 Redis 一样按 0 处理
+	if sum := acc + score; !math.IsNaN(sum) {
+		return sum
+	}
+	return 0
+}
+
+// ZSetAlgebraCommand 实现 ZUNION、ZINTER、ZDIFF 及其 STORE 形式
+// 输入可以是有序集合，也可以是普通集合（成员分数视为 1）
+type ZSetAlgebraCommand struct {
+	zsetOps  store.ZSetOps
+	setOps   store.SetOps
+	keyspace *Keyspace
+	name     string
+	op       setOperation
+	store    bool
+}
+
+func NewZUnionCommand(z store.ZSetOps, s store.SetOps) *ZSetAlgebraCommand {
+	return &ZSetAlgebraCommand{zsetOps: z, setOps: s, name: "ZUNION", op: setUnion}
+}
+
+func NewZInterCommand(z store.ZSetOps, s store.SetOps) *ZSetAlgebraCommand {
+	return &ZSetAlgebraCommand{zsetOps: z, setOps: s, name: "ZINTER", op: setInter}
+}
+
+func NewZDiffCommand(z store.ZSetOps, s store.SetOps) *ZSetAlgebraCommand {
+	return &ZSetAlgebraCommand{zsetOps: z, setOps: s, name: "ZDIFF", op: setDiff}
+}
+
+func NewZUnionStoreCommand(z store.ZSetOps, s store.SetOps, k *Keyspace) *ZSetAlgebraCommand {
+	return &ZSetAlgebraCommand{zsetOps: z, setOps: s, keyspace: k, name: "ZUNIONSTORE", op: setUnion, store: true}
+}
+
+func NewZInterStoreCommand(z store.ZSetOps, s store.SetOps, k *Keyspace) *ZSetAlgebraCommand {
+	return &ZSetAlgebraCommand{zsetOps: z, setOps: s, keyspace: k, name: "ZINTERSTORE", op: setInter, store: true}
+}
+
+func NewZDiffStoreCommand(z store.ZSetOps, s store.SetOps, k *Keyspace) *ZSetAlgebraCommand {
+	return &ZSetAlgebraCommand{zsetOps: z, setOps: s, keyspace: k, name: "ZDIFFSTORE", op: setDiff, store: true}
+}
+
+func (c *ZSetAlgebraCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	var dst string
+	if c.store {
+		if len(args) < 3 {
+			return nil, wrongArgs(c.name)
+		}
+		dst, args = args[0], args[1:]
+	} else if len(args) < 2 {
+		return nil, wrongArgs(c.name)
+	}
+	keys, rest, err := parseNumKeys(args)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make([]float64, len(keys))
+	for i := range weights {
+		weights[i] = 1
+	}
+	aggregate := aggregateSum
+	withScores := false
+	for i := 0; i < len(rest); i++ {
+		opt := strings.ToUpper(rest[i])
+		switch {
+		case opt == "WEIGHTS" && c.op != setDiff && i+len(keys) < len(rest):
+			for j := range weights {
+				w, ok := store.ParseScore(rest[i+1+j])
+				if !ok {
+					return nil, fmt.Errorf("weight value is not a float")
+				}
+				weights[j] = w
+			}
+			i += len(keys)
+		case opt == "AGGREGATE" && c.op != setDiff && i+1 < len(rest):
+			switch strings.ToUpper(rest[i+1]) {
+			case "SUM":
+				aggregate = aggregateSum
+			case "MIN":
+				aggregate = aggregateMin
+			case "MAX":
+				aggregate = aggregateMax
+			default:
+				return nil, fmt.Errorf("syntax error")
+			}
+			i++
+		case opt == "WITHSCORES" && !c.store:
+			withScores = true
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+
+	inputs := make([]map[string]float64, len(keys))
+	for i, key := range keys {
+		inputs[i] = loadScoredSet(c.zsetOps, c.setOps, key)
+	}
+	result := zsetCombine(c.op, inputs, weights, aggregate)
+
+	if c.store {
+		c.keyspace.DeleteOtherTypes(dst, "zset")
+		return resp.Integer(c.zsetOps.StoreElements(dst, result)), nil
+	}
+	return encodeScoreMembers(ctx, result, withScores), nil
+}
+
+// loadScoredSet 读取有序集合或普通集合（成员分数视为 1），键不存在时返回空集合
+func loadScoredSet(zsetOps store.ZSetOps, setOps store.SetOps, key string) map[string]float64 {
+	scored := make(map[string]float64)
+	if zsetOps.Exists(key) {
+		for _, e := range zsetOps.Range(key, store.RangeSpec{By: store.ByRank, Start: 0, Stop: -1}) {
+			scored[e.Member] = e.Score
+		}
+		return scored
+	}
+	for _, member := range setOps.Members(key) {
+		scored[member] = 1
+	}
+	return scored
+}
+
+// zsetCombine 计算多个带分数集合的并集、交集或差集，结果按分数和成员排序
+// 差集只保留第一个集合的原始分数，不使用权重和聚合方式
+func zsetCombine(op setOperation, inputs []map[string]float64, weights []float64, aggregate zsetAggregate) []store.ScoreMember {
+	weighted := func(i int, score float64) float64 {
+		// 0 * inf 得到 NaN，按 0 处理
+		if v := score * weights[i]; !math.IsNaN(v) {
+			return v
+		}
+		return 0
+	}
+
+	acc := make(map[string]float64)
+	switch op {
+	case setUnion:
+		for i, input := range inputs {
+			for member, score := range input {
+				if cur, ok := acc[member]; ok {
+					acc[member] = aggregate.apply(cur, weighted(i, score))
+				} else {
+					acc[member] = weighted(i, score)
+				}
+			}
+		}
+	case setInter:
+		for member, score := range inputs[0] {
+			total, inAll := weighted(0, score), true
+			for i, other := range inputs[1:] {
+				s, ok := other[member]
+				if !ok {
+					inAll = false
+					break
+				}
+				total = aggregate.apply(total, weighted(i+1, s))
+			}
+			if inAll {
+				acc[member] = total
+			}
+		}
+	default:
+		for member, score := range inputs[0] {
+			found := false
+			for _, other := range inputs[1:] {
+				if _, found = other[member]; found {
+					break
+				}
+			}
+			if !found {
+				acc[member] = score
+			}
+		}
+	}
+
+	result := make([]store.ScoreMember, 0, len(acc))
+	for member, score := range acc {
+		result = append(result, store.ScoreMember{Member: member, Score: score})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score < result[j].Score
+		}
+		return result[i].Member < result[j].Member
+	})
+	return result
+}
+
+type ZInterCardCommand struct {
+	zsetOps store.ZSetOps
+	setOps  store.SetOps
+}
+
+func NewZInterCardCommand(z store.ZSetOps, s store.SetOps) *ZInterCardCommand {
+	return &ZInterCardCommand{
+		zsetOps: z,
+		setOps:  s,
+	}
+}
+
+func (c *ZInterCardCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("zintercard")
+	}
+	keys, rest, err := parseNumKeys(args)
+	if err != nil {
+		return nil, err
+	}
+	limit := 0
+	for i := 0; i < len(rest); i++ {
+		if strings.ToUpper(rest[i]) != "LIMIT" || i+1 >= len(rest) {
+			return nil, fmt.Errorf("syntax error")
+		}
+		limit, err = strconv.Atoi(rest[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		if limit < 0 {
+			return nil, fmt.Errorf("LIMIT can't be negative")
+		}
+		i++
+	}
+
+	inputs := make([]map[string]float64, len(keys))
+	for i, key := range keys {
+		inputs[i] = loadScoredSet(c.zsetOps, c.setOps, key)
+	}
+	card := 0
+	for member := range inputs[0] {
+		inAll := true
+		for _, other := range inputs[1:] {
+			if _, ok := other[member]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			card++
+			if limit > 0 && card >= limit {
+				break
+			}
+		}
+	}
+	return resp.Integer(card), nil
+}
+
+type ZRandMemberCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZRandMemberCommand(z store.ZSetOps) *ZRandMemberCommand {
+	return &ZRandMemberCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZRandMemberCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nil, wrongArgs("zrandmember")
+	}
+	key := args[0]
+	// 不带 count：返回单个成员或 null
+	if len(args) == 1 {
+		elements := c.zsetOps.RandomMembers(key, 1, false)
+		if len(elements) == 0 {
+			return resp.Null{}, nil
+		}
+		return resp.BulkString(elements[0].Member), nil
+	}
+	count, allowRepeats, err := parseRandomCount(args[1])
+	if err != nil {
+		return nil, err
+	}
+	withScores := false
+	if len(args) == 3 {
+		if strings.ToUpper(args[2]) != "WITHSCORES" {
+			return nil, fmt.Errorf("syntax error")
+		}
+		withScores = true
+	}
+	return encodeScoreMembers(ctx, c.zsetOps.RandomMembers(key, count, allowRepeats), withScores), nil
+}
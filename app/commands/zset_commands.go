@@ -0,0 +1,301 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"strings"
+)
+
+type ZAddCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZAddCommand(z store.ZSetOps) *ZAddCommand {
+	return &ZAddCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZAddCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 3 {
+		return nil, wrongArgs("zadd")
+	}
+	key := args[0]
+	var opts store.ZAddOptions
+	ch := false
+	i := 1
+flags:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			opts.NX = true
+		case "XX":
+			opts.XX = true
+		case "GT":
+			opts.GT = true
+		case "LT":
+			opts.LT = true
+		case "CH":
+			ch = true
+		case "INCR":
+			opts.Incr = true
+		default:
+			break flags
+		}
+	}
+	rest := args[i:]
+	if len(rest) == 0 || len(rest)%2 != 0 {
+		return nil, fmt.Errorf("syntax error")
+	}
+	if opts.NX && opts.XX {
+		return nil, fmt.Errorf("XX and NX options at the same time are not compatible")
+	}
+	if (opts.GT && opts.LT) || (opts.GT && opts.NX) || (opts.LT && opts.NX) {
+		return nil, fmt.Errorf("GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if opts.Incr && len(rest) > 2 {
+		return nil, fmt.Errorf("INCR option supports a single increment-element pair")
+	}
+	elements := make([]store.ScoreMember, 0, len(rest)/2)
+	for j := 0; j < len(rest); j += 2 {
+		score, ok := store.ParseScore(rest[j])
+		if !ok {
+			return nil, fmt.Errorf("value is not a valid float")
+		}
+		elements = append(elements, store.ScoreMember{Member: rest[j+1], Score: score})
+	}
+
+	result, err := c.zsetOps.Add(key, elements, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Incr {
+		if !result.Applied {
+			return resp.Null{}, nil
+		}
+		return resp.Double(result.Score), nil
+	}
+	if ch {
+		return resp.Integer(result.Changed), nil
+	}
+	return resp.Integer(result.Added), nil
+}
+
+type ZIncrByCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZIncrByCommand(z store.ZSetOps) *ZIncrByCommand {
+	return &ZIncrByCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZIncrByCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 {
+		return nil, wrongArgs("zincrby")
+	}
+	delta, ok := store.ParseScore(args[1])
+	if !ok {
+		return nil, fmt.Errorf("value is not a valid float")
+	}
+	result, err := c.zsetOps.Add(args[0], []store.ScoreMember{{Member: args[2], Score: delta}}, store.ZAddOptions{Incr: true})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Double(result.Score), nil
+}
+
+type ZScoreCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZScoreCommand(z store.ZSetOps) *ZScoreCommand {
+	return &ZScoreCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZScoreCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 2 {
+		return nil, wrongArgs("zscore")
+	}
+	score, ok := c.zsetOps.Score(args[0], args[1])
+	if !ok {
+		return resp.Null{}, nil
+	}
+	return resp.Double(score), nil
+}
+
+type ZMScoreCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZMScoreCommand(z store.ZSetOps) *ZMScoreCommand {
+	return &ZMScoreCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZMScoreCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("zmscore")
+	}
+	scores, found := c.zsetOps.Scores(args[0], args[1:])
+	elements := make(resp.Array, len(scores))
+	for i, score := range scores {
+		if found[i] {
+			elements[i] = resp.Double(score)
+		} else {
+			elements[i] = resp.Null{}
+		}
+	}
+	return elements, nil
+}
+
+// ZRankCommand 实现 ZRANK 和 ZREVRANK
+type ZRankCommand struct {
+	zsetOps store.ZSetOps
+	name    string
+	reverse bool
+}
+
+func NewZRankCommand(z store.ZSetOps) *ZRankCommand {
+	return &ZRankCommand{zsetOps: z, name: "ZRANK"}
+}
+
+func NewZRevRankCommand(z store.ZSetOps) *ZRankCommand {
+	return &ZRankCommand{zsetOps: z, name: "ZREVRANK", reverse: true}
+}
+
+func (c *ZRankCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, wrongArgs(c.name)
+	}
+	withScore := false
+	if len(args) == 3 {
+		if strings.ToUpper(args[2]) != "WITHSCORE" {
+			return nil, fmt.Errorf("syntax error")
+		}
+		withScore = true
+	}
+	rank, score, ok := c.zsetOps.Rank(args[0], args[1], c.reverse)
+	if !ok {
+		if withScore {
+			return resp.NullArray{}, nil
+		}
+		return resp.Null{}, nil
+	}
+	if withScore {
+		return resp.Array{resp.Integer(rank), resp.Double(score)}, nil
+	}
+	return resp.Integer(rank), nil
+}
+
+type ZRemCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZRemCommand(z store.ZSetOps) *ZRemCommand {
+	return &ZRemCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZRemCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("zrem")
+	}
+	return resp.Integer(c.zsetOps.Remove(args[0], args[1:])), nil
+}
+
+type ZCardCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZCardCommand(z store.ZSetOps) *ZCardCommand {
+	return &ZCardCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZCardCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 1 {
+		return nil, wrongArgs("zcard")
+	}
+	return resp.Integer(c.zsetOps.Card(args[0])), nil
+}
+
+type ZCountCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZCountCommand(z store.ZSetOps) *ZCountCommand {
+	return &ZCountCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZCountCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 {
+		return nil, wrongArgs("zcount")
+	}
+	r, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(c.zsetOps.Count(args[0], r)), nil
+}
+
+type ZScanCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZScanCommand(z store.ZSetOps) *ZScanCommand {
+	return &ZScanCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZScanCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("zscan")
+	}
+	opts, err := parseScanArgs(args[1:], false)
+	if err != nil {
+		return nil, err
+	}
+	elements, next := c.zsetOps.Scan(args[0], opts.cursor, opts.match, opts.count)
+	return encodeScanReply(next, flattenScoreMembers(elements)), nil
+}
+
+// parseScoreRange 解析分数区间，端点前加 "(" 表示不包含该端点
+func parseScoreRange(min, max string) (store.ScoreRange, error) {
+	var r store.ScoreRange
+	var okMin, okMax bool
+	r.Min, r.MinEx, okMin = parseScoreBound(min)
+	r.Max, r.MaxEx, okMax = parseScoreBound(max)
+	if !okMin || !okMax {
+		return r, fmt.Errorf("min or max is not a float")
+	}
+	return r, nil
+}
+
+func parseScoreBound(s string) (float64, bool, bool) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	score, ok := store.ParseScore(s)
+	return score, exclusive, ok
+}
+
+// flattenScoreMembers 将成员和分数展开为 member score member score ... 的形式
+func flattenScoreMembers(elements []store.ScoreMember) []string {
+	result := make([]string, 0, len(elements)*2)
+	for _, e := range elements {
+		result = append(result, e.Member, resp.FormatDouble(e.Score))
+	}
+	return result
+}
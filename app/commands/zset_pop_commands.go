@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"strconv"
+	"strings"
+)
+
+// ZPopCommand 实现 ZPOPMIN 和 ZPOPMAX
+type ZPopCommand struct {
+	zsetOps store.ZSetOps
+	name    string
+	max     bool
+}
+
+func NewZPopMinCommand(z store.ZSetOps) *ZPopCommand {
+	return &ZPopCommand{zsetOps: z, name: "ZPOPMIN"}
+}
+
+func NewZPopMaxCommand(z store.ZSetOps) *ZPopCommand {
+	return &ZPopCommand{zsetOps: z, name: "ZPOPMAX", max: true}
+}
+
+func (c *ZPopCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, wrongArgs(c.name)
+	}
+	count := 1
+	if len(args) == 2 {
+		var err error
+		count, err = strconv.Atoi(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("value is out of range, must be positive")
+		}
+	}
+	_, popped := c.zsetOps.Pop(args[:1], count, c.max)
+	// 与 Redis 一样，RESP3 下不带 count 时返回单个 [member, score]
+	if len(args) == 1 && len(popped) == 1 && ctx.RESP3() {
+		return encodeScoreMember(popped[0]), nil
+	}
+	return encodeScoreMembers(ctx, popped, true), nil
+}
+
+// BZPopCommand 实现 BZPOPMIN 和 BZPOPMAX
+type BZPopCommand struct {
+	zsetOps store.ZSetOps
+	name    string
+	max     bool
+}
+
+func NewBZPopMinCommand(z store.ZSetOps) *BZPopCommand {
+	return &BZPopCommand{zsetOps: z, name: "BZPOPMIN"}
+}
+
+func NewBZPopMaxCommand(z store.ZSetOps) *BZPopCommand {
+	return &BZPopCommand{zsetOps: z, name: "BZPOPMAX", max: true}
+}
+
+func (c *BZPopCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs(c.name)
+	}
+	keys := args[:len(args)-1]
+	timeout, err := parseBlockTimeout(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	blockCtx, done := ctx.BeginBlocking()
+	defer done()
+	key, popped, err := c.zsetOps.BlockingPop(blockCtx, keys, 1, c.max, timeout)
+	timedOut, err := blockingError(err)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.PropagateAs()
+	if timedOut || len(popped) == 0 {
+		return resp.NullArray{}, nil
+	}
+	propagatePop(ctx, key, len(popped), c.max)
+	return resp.Array{resp.BulkString(key), resp.BulkString(popped[0].Member), resp.Double(popped[0].Score)}, nil
+}
+
+// ZMPopCommand 实现 ZMPOP 和 BZMPOP
+type ZMPopCommand struct {
+	zsetOps  store.ZSetOps
+	name     string
+	blocking bool
+}
+
+func NewZMPopCommand(z store.ZSetOps) *ZMPopCommand {
+	return &ZMPopCommand{zsetOps: z, name: "ZMPOP"}
+}
+
+func NewBZMPopCommand(z store.ZSetOps) *ZMPopCommand {
+	return &ZMPopCommand{zsetOps: z, name: "BZMPOP", blocking: true}
+}
+
+func (c *ZMPopCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	minArgs := 3
+	if c.blocking {
+		minArgs = 4
+	}
+	if len(args) < minArgs {
+		return nil, wrongArgs(c.name)
+	}
+
+	var timeout = "0"
+	if c.blocking {
+		timeout, args = args[0], args[1:]
+	}
+	keys, rest, err := parseNumKeys(args)
+	if err != nil {
+		return nil, err
+	}
+	max, count, err := parsePopWhere(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	var key string
+	var popped []store.ScoreMember
+	if c.blocking {
+		d, err := parseBlockTimeout(timeout)
+		if err != nil {
+			return nil, err
+		}
+		blockCtx, done := ctx.BeginBlocking()
+		defer done()
+		key, popped, err = c.zsetOps.BlockingPop(blockCtx, keys, count, max, d)
+		if _, err = blockingError(err); err != nil {
+			return nil, err
+		}
+	} else {
+		key, popped = c.zsetOps.Pop(keys, count, max)
+	}
+
+	ctx.PropagateAs()
+	if len(popped) == 0 {
+		return resp.NullArray{}, nil
+	}
+	propagatePop(ctx, key, len(popped), max)
+	elements := make(resp.Array, len(popped))
+	for i, e := range popped {
+		elements[i] = encodeScoreMember(e)
+	}
+	return resp.Array{resp.BulkString(key), elements}, nil
+}
+
+// parsePopWhere 解析 MIN|MAX [COUNT count]
+func parsePopWhere(args []string) (bool, int, error) {
+	if len(args) == 0 {
+		return false, 0, fmt.Errorf("syntax error")
+	}
+	var max bool
+	switch strings.ToUpper(args[0]) {
+	case "MIN":
+	case "MAX":
+		max = true
+	default:
+		return false, 0, fmt.Errorf("syntax error")
+	}
+	count := 1
+	switch {
+	case len(args) == 1:
+	case len(args) == 3 && strings.ToUpper(args[1]) == "COUNT":
+		var err error
+		count, err = strconv.Atoi(args[2])
+		if err != nil || count <= 0 {
+			return false, 0, fmt.Errorf("count should be greater than 0")
+		}
+	default:
+		return false, 0, fmt.Errorf("syntax error")
+	}
+	return max, count, nil
+}
+
+// propagatePop 把阻塞或多键弹出改写为对实际弹出的键执行的 ZPOPMIN/ZPOPMAX 传播
+func propagatePop(ctx *ConnectionContext, key string, count int, max bool) {
+	cmd := "ZPOPMIN"
+	if max {
+		cmd = "ZPOPMAX"
+	}
+	ctx.PropagateAs([]string{cmd, key, strconv.Itoa(count)})
+}
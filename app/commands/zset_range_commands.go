@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"strconv"
+	"strings"
+)
+
+// ZRangeCommand 实现 ZRANGE、ZRANGESTORE 以及旧式的 ZREVRANGE、ZRANGEBYSCORE、
+// ZREVRANGEBYSCORE、ZRANGEBYLEX 和 ZREVRANGEBYLEX
+type ZRangeCommand struct {
+	zsetOps  store.ZSetOps
+	keyspace *Keyspace
+	name     string
+	by       store.RangeBy
+	reverse  bool
+	unified  bool // 区间类型和方向由 BYSCORE/BYLEX/REV 参数指定
+	store    bool // 将结果保存到目标键（ZRANGESTORE）
+}
+
+func NewZRangeCommand(z store.ZSetOps) *ZRangeCommand {
+	return &ZRangeCommand{zsetOps: z, name: "ZRANGE", unified: true}
+}
+
+func NewZRangeStoreCommand(z store.ZSetOps, k *Keyspace) *ZRangeCommand {
+	return &ZRangeCommand{zsetOps: z, keyspace: k, name: "ZRANGESTORE", unified: true, store: true}
+}
+
+func NewZRevRangeCommand(z store.ZSetOps) *ZRangeCommand {
+	return &ZRangeCommand{zsetOps: z, name: "ZREVRANGE", reverse: true}
+}
+
+func NewZRangeByScoreCommand(z store.ZSetOps) *ZRangeCommand {
+	return &ZRangeCommand{zsetOps: z, name: "ZRANGEBYSCORE", by: store.ByScore}
+}
+
+func NewZRevRangeByScoreCommand(z store.ZSetOps) *ZRangeCommand {
+	return &ZRangeCommand{zsetOps: z, name: "ZREVRANGEBYSCORE", by: store.ByScore, reverse: true}
+}
+
+func NewZRangeByLexCommand(z store.ZSetOps) *ZRangeCommand {
+	return &ZRangeCommand{zsetOps: z, name: "ZRANGEBYLEX", by: store.ByLex}
+}
+
+func NewZRevRangeByLexCommand(z store.ZSetOps) *ZRangeCommand {
+	return &ZRangeCommand{zsetOps: z, name: "ZREVRANGEBYLEX", by: store.ByLex, reverse: true}
+}
+
+func (c *ZRangeCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	var dst string
+	if c.store {
+		if len(args) < 4 {
+			return nil, wrongArgs(c.name)
+		}
+		dst, args = args[0], args[1:]
+	} else if len(args) < 3 {
+		return nil, wrongArgs(c.name)
+	}
+
+	spec := store.RangeSpec{By: c.by, Reverse: c.reverse, Count: -1}
+	withScores, limit := false, false
+	for i := 3; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch {
+		case opt == "WITHSCORES" && !c.store:
+			withScores = true
+		case opt == "LIMIT" && i+2 < len(args):
+			offset, err1 := strconv.Atoi(args[i+1])
+			count, err2 := strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			spec.Offset, spec.Count, limit = offset, count, true
+			i += 2
+		case opt == "BYSCORE" && c.unified:
+			spec.By = store.ByScore
+		case opt == "BYLEX" && c.unified:
+			spec.By = store.ByLex
+		case opt == "REV" && c.unified:
+			spec.Reverse = true
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	if limit && spec.By == store.ByRank {
+		return nil, fmt.Errorf("syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if withScores && spec.By == store.ByLex {
+		return nil, fmt.Errorf("syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+
+	key, min, max := args[0], args[1], args[2]
+	// 按分数或字典序逆序查询时，参数顺序为 max min
+	if spec.Reverse && spec.By != store.ByRank {
+		min, max = max, min
+	}
+	if err := parseRangeBounds(&spec, min, max); err != nil {
+		return nil, err
+	}
+
+	elements := c.zsetOps.Range(key, spec)
+	if c.store {
+		c.keyspace.DeleteOtherTypes(dst, "zset")
+		return resp.Integer(c.zsetOps.StoreElements(dst, elements)), nil
+	}
+	return encodeScoreMembers(ctx, elements, withScores), nil
+}
+
+// ZRemRangeCommand 实现 ZREMRANGEBYRANK、ZREMRANGEBYSCORE 和 ZREMRANGEBYLEX
+type ZRemRangeCommand struct {
+	zsetOps store.ZSetOps
+	name    string
+	by      store.RangeBy
+}
+
+func NewZRemRangeByRankCommand(z store.ZSetOps) *ZRemRangeCommand {
+	return &ZRemRangeCommand{zsetOps: z, name: "ZREMRANGEBYRANK", by: store.ByRank}
+}
+
+func NewZRemRangeByScoreCommand(z store.ZSetOps) *ZRemRangeCommand {
+	return &ZRemRangeCommand{zsetOps: z, name: "ZREMRANGEBYSCORE", by: store.ByScore}
+}
+
+func NewZRemRangeByLexCommand(z store.ZSetOps) *ZRemRangeCommand {
+	return &ZRemRangeCommand{zsetOps: z, name: "ZREMRANGEBYLEX", by: store.ByLex}
+}
+
+func (c *ZRemRangeCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 {
+		return nil, wrongArgs(c.name)
+	}
+	spec := store.RangeSpec{By: c.by, Count: -1}
+	if err := parseRangeBounds(&spec, args[1], args[2]); err != nil {
+		return nil, err
+	}
+	return resp.Integer(c.zsetOps.RemoveRange(args[0], spec)), nil
+}
+
+type ZLexCountCommand struct {
+	zsetOps store.ZSetOps
+}
+
+func NewZLexCountCommand(z store.ZSetOps) *ZLexCountCommand {
+	return &ZLexCountCommand{
+		zsetOps: z,
+	}
+}
+
+func (c *ZLexCountCommand) Handle(ctx *ConnectionContext, args []string) (resp.Reply, error) {
+	if len(args) != 3 {
+		return nil, wrongArgs("zlexcount")
+	}
+	r, err := parseLexRange(args[1], args[2])
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(c.zsetOps.LexCount(args[0], r)), nil
+}
+
+// parseRangeBounds 按 spec.By 解析区间的两个端点
+func parseRangeBounds(spec *store.RangeSpec, min, max string) error {
+	var err error
+	switch spec.By {
+	case store.ByScore:
+		spec.Score, err = parseScoreRange(min, max)
+	case store.ByLex:
+		spec.Lex, err = parseLexRange(min, max)
+	default:
+		start, err1 := strconv.Atoi(min)
+		stop, err2 := strconv.Atoi(max)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("value is not an integer or out of range")
+		}
+		spec.Start, spec.Stop = start, stop
+	}
+	return err
+}
+
+// parseLexRange 解析字典序区间，端点必须以 "(" 或 "[" 开头，或者是 "-"、"+"
+func parseLexRange(min, max string) (store.LexRange, error) {
+	var r store.LexRange
+	var okMin, okMax bool
+	r.Min, okMin = parseLexBound(min)
+	r.Max, okMax = parseLexBound(max)
+	if !okMin || !okMax {
+		return r, fmt.Errorf("min or max not valid string range item")
+	}
+	return r, nil
+}
+
+func parseLexBound(s string) (store.LexBound, bool) {
+	switch {
+	case s == "-":
+		return store.LexBound{Inf: -1}, true
+	case s == "+":
+		return store.LexBound{Inf: 1}, true
+	case strings.HasPrefix(s, "("):
+		return store.LexBound{Value: s[1:], Exclusive: true}, true
+	case strings.HasPrefix(s, "["):
+		return store.LexBound{Value: s[1:]}, true
+	}
+	return store.LexBound{}, false
+}
+
+// encodeScoreMembers 构建成员列表，withScores 为 true 时每个成员后跟分数
+// RESP3 下每个成员和分数组成一个两元素数组
+func encodeScoreMembers(ctx *ConnectionContext, elements []store.ScoreMember, withScores bool) resp.Reply {
+	if withScores && ctx.RESP3() {
+		pairs := make(resp.Array, len(elements))
+		for i, e := range elements {
+			pairs[i] = encodeScoreMember(e)
+		}
+		return pairs
+	}
+	if withScores {
+		flat := make(resp.Array, 0, len(elements)*2)
+		for _, e := range elements {
+			flat = append(flat, resp.BulkString(e.Member), resp.Double(e.Score))
+		}
+		return flat
+	}
+	members := make([]string, len(elements))
+	for i, e := range elements {
+		members[i] = e.Member
+	}
+	return resp.StringArray(members)
+}
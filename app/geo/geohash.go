@@ -0,0 +1,171 @@
+// Package geo 实现与 Redis 相同的 52 位交错 geohash 编码以及范围搜索所需的计算，
+// 使有序集合中保存的分数与 Redis 完全兼容
+package geo
+
+import "math"
+
+const (
+	StepMax = 26 // 每个坐标 26 位，交错后共 52 位
+
+	LatMin  = -85.05112878 // Web Mercator 投影可表示的纬度范围
+	LatMax  = 85.05112878
+	LongMin = -180.0
+	LongMax = 180.0
+
+	earthRadiusMeters = 6372797.560856 // 与 Redis 相同的地球半径
+	mercatorMax       = 20037726.37
+)
+
+// hashBits 表示 step 精度下的 geohash，step 为每个坐标使用的位数
+type hashBits struct {
+	bits uint64
+	step uint
+}
+
+func (h hashBits) isZero() bool {
+	return h.bits == 0 && h.step == 0
+}
+
+type coordRange struct {
+	min, max float64
+}
+
+// area 表示 geohash 对应的经纬度矩形
+type area struct {
+	longitude, latitude coordRange
+}
+
+var (
+	wgs84Long = coordRange{LongMin, LongMax}
+	wgs84Lat  = coordRange{LatMin, LatMax}
+)
+
+// interleave64 交错两个 32 位整数：x 占偶数位，y 占奇数位
+func interleave64(x, y uint32) uint64 {
+	spread := func(v uint64) uint64 {
+		v = (v | (v << 16)) & 0x0000FFFF0000FFFF
+		v = (v | (v << 8)) & 0x00FF00FF00FF00FF
+		v = (v | (v << 4)) & 0x0F0F0F0F0F0F0F0F
+		v = (v | (v << 2)) & 0x3333333333333333
+		v = (v | (v << 1)) & 0x5555555555555555
+		return v
+	}
+	return spread(uint64(x)) | (spread(uint64(y)) << 1)
+}
+
+// deinterleave64 是 interleave64 的逆运算，返回 (x, y)
+func deinterleave64(v uint64) (uint32, uint32) {
+	squash := func(v uint64) uint64 {
+		v &= 0x5555555555555555
+		v = (v | (v >> 1)) & 0x3333333333333333
+		v = (v | (v >> 2)) & 0x0F0F0F0F0F0F0F0F
+		v = (v | (v >> 4)) & 0x00FF00FF00FF00FF
+		v = (v | (v >> 8)) & 0x0000FFFF0000FFFF
+		v = (v | (v >> 16)) & 0x00000000FFFFFFFF
+		return v
+	}
+	return uint32(squash(v)), uint32(squash(v >> 1))
+}
+
+// encode 按给定范围和精度编码经纬度，纬度占偶数位、经度占奇数位
+func encode(longRange, latRange coordRange, longitude, latitude float64, step uint) (hashBits, bool) {
+	if longitude > LongMax || longitude < LongMin || latitude > LatMax || latitude < LatMin {
+		return hashBits{}, false
+	}
+	if latitude < latRange.min || latitude > latRange.max ||
+		longitude < longRange.min || longitude > longRange.max {
+		return hashBits{}, false
+	}
+	latOffset := (latitude - latRange.min) / (latRange.max - latRange.min)
+	longOffset := (longitude - longRange.min) / (longRange.max - longRange.min)
+	latOffset *= float64(uint64(1) << step)
+	longOffset *= float64(uint64(1) << step)
+	return hashBits{bits: interleave64(uint32(latOffset), uint32(longOffset)), step: step}, true
+}
+
+// decode 返回 geohash 对应的经纬度矩形
+func decode(longRange, latRange coordRange, hash hashBits) area {
+	ilat, ilong := deinterleave64(hash.bits)
+	latScale := latRange.max - latRange.min
+	longScale := longRange.max - longRange.min
+	cells := float64(uint64(1) << hash.step)
+	return area{
+		latitude: coordRange{
+			min: latRange.min + (float64(ilat)/cells)*latScale,
+			max: latRange.min + ((float64(ilat)+1)/cells)*latScale,
+		},
+		longitude: coordRange{
+			min: longRange.min + (float64(ilong)/cells)*longScale,
+			max: longRange.min + ((float64(ilong)+1)/cells)*longScale,
+		},
+	}
+}
+
+// align52 把 hash 左移到 52 位，作为有序集合中的分数
+func (h hashBits) align52() uint64 {
+	return h.bits << (52 - h.step*2)
+}
+
+// Encode 把经纬度编码为 52 位的有序集合分数，坐标超出范围时返回 false
+func Encode(longitude, latitude float64) (uint64, bool) {
+	hash, ok := encode(wgs84Long, wgs84Lat, longitude, latitude, StepMax)
+	if !ok {
+		return 0, false
+	}
+	return hash.align52(), true
+}
+
+// Decode 把 52 位分数解码为所在格子中心点的经纬度
+func Decode(score uint64) (float64, float64) {
+	a := decode(wgs84Long, wgs84Lat, hashBits{bits: score, step: StepMax})
+	longitude := math.Max(LongMin, math.Min(LongMax, (a.longitude.min+a.longitude.max)/2))
+	latitude := math.Max(LatMin, math.Min(LatMax, (a.latitude.min+a.latitude.max)/2))
+	return longitude, latitude
+}
+
+const base32Alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// HashString 返回标准 geohash 字符串（11 个字符，纬度范围为 ±90）
+func HashString(score uint64) string {
+	longitude, latitude := Decode(score)
+	hash, _ := encode(coordRange{-180, 180}, coordRange{-90, 90}, longitude, latitude, StepMax)
+	buf := make([]byte, 11)
+	for i := range buf {
+		idx := 0
+		// 52 位只够 10 个字符，最后一个字符补 0
+		if i < 10 {
+			idx = int((hash.bits >> (52 - (uint(i)+1)*5)) & 0x1f)
+		}
+		buf[i] = base32Alphabet[idx]
+	}
+	return string(buf)
+}
+
+func degRad(ang float64) float64 {
+	return ang * (math.Pi / 180.0)
+}
+
+func radDeg(ang float64) float64 {
+	return ang / (math.Pi / 180.0)
+}
+
+// latDistance 返回两个纬度之间的南北距离（米）
+func latDistance(lat1, lat2 float64) float64 {
+	return earthRadiusMeters * math.Abs(degRad(lat2)-degRad(lat1))
+}
+
+// Distance 用 haversine 公式计算两点之间的距离（米）
+func Distance(lon1, lat1, lon2, lat2 float64) float64 {
+	lon1r := degRad(lon1)
+	lon2r := degRad(lon2)
+	v := math.Sin((lon2r - lon1r) / 2)
+	// 经度相同时只需计算纬度方向的距离
+	if v == 0 {
+		return latDistance(lat1, lat2)
+	}
+	lat1r := degRad(lat1)
+	lat2r := degRad(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	a := u*u + math.Cos(lat1r)*math.Cos(lat2r)*v*v
+	return 2.0 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,64 @@
+package geo
+
+import (
+	"fmt"
+	"testing"
+)
+
+// sicily 是 Redis 文档 GEOADD/GEOHASH/GEOPOS/GEODIST 示例中的成员，期望值取自真实 Redis 的输出
+var sicily = []struct {
+	name                string
+	longitude, latitude float64
+	score               uint64
+	hash                string
+	pos                 [2]string // GEOPOS 返回的经纬度
+}{
+	{"Palermo", 13.361389, 38.115556, 3479099956230698, "sqc8b49rny0", [2]string{"13.36138933897018433", "38.11555639549629859"}},
+	{"Catania", 15.087269, 37.502669, 3479447370796909, "sqdtr74hyu0", [2]string{"15.08726745843887329", "37.50266842333162032"}},
+}
+
+func TestEncodeMatchesRedis(t *testing.T) {
+	for _, m := range sicily {
+		score, ok := Encode(m.longitude, m.latitude)
+		if !ok || score != m.score {
+			t.Errorf("Encode(%s) = %d, %v, want %d", m.name, score, ok, m.score)
+		}
+		if got := HashString(m.score); got != m.hash {
+			t.Errorf("HashString(%s) = %s, want %s", m.name, got, m.hash)
+		}
+		longitude, latitude := Decode(m.score)
+		if got := [2]string{fmt.Sprintf("%.17f", longitude), fmt.Sprintf("%.17f", latitude)}; got != m.pos {
+			t.Errorf("Decode(%s) = %v, want %v", m.name, got, m.pos)
+		}
+	}
+}
+
+func TestEncodeOutOfRange(t *testing.T) {
+	for _, p := range [][2]float64{{181, 0}, {-181, 0}, {0, 85.06}, {0, -85.06}} {
+		if _, ok := Encode(p[0], p[1]); ok {
+			t.Errorf("Encode(%v, %v) should fail", p[0], p[1])
+		}
+	}
+}
+
+func TestDistanceMatchesRedis(t *testing.T) {
+	palermoLon, palermoLat := Decode(sicily[0].score)
+	cataniaLon, cataniaLat := Decode(sicily[1].score)
+	tests := []struct {
+		name                   string
+		lon1, lat1, lon2, lat2 float64
+		want                   string // GEODIST/WITHDIST 的输出
+		unit                   float64
+	}{
+		{"GEODIST Palermo Catania", palermoLon, palermoLat, cataniaLon, cataniaLat, "166274.1516", 1},
+		{"GEODIST Palermo Catania km", palermoLon, palermoLat, cataniaLon, cataniaLat, "166.2742", 1000},
+		{"GEODIST Palermo Catania mi", palermoLon, palermoLat, cataniaLon, cataniaLat, "103.3182", 1609.34},
+		{"GEORADIUS 15 37 Palermo", 15, 37, palermoLon, palermoLat, "190.4424", 1000},
+		{"GEORADIUS 15 37 Catania", 15, 37, cataniaLon, cataniaLat, "56.4413", 1000},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf("%.4f", Distance(tt.lon1, tt.lat1, tt.lon2, tt.lat2)/tt.unit); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package geo
+
+import "math"
+
+// Shape 表示搜索区域：以 (Longitude, Latitude) 为中心的圆形或矩形，长度单位均为米
+type Shape struct {
+	Longitude, Latitude float64
+	Box                 bool    // true 表示矩形（BYBOX），否则为圆形（BYRADIUS）
+	Radius              float64 // 圆形的半径
+	Width, Height       float64 // 矩形的宽和高
+}
+
+// Contains 判断点是否在区域内，在区域内时返回点到中心的距离（米）
+func (s Shape) Contains(longitude, latitude float64) (float64, bool) {
+	if s.Box {
+		// 纬度方向的距离计算更简单，先检查
+		if latDistance(latitude, s.Latitude) > s.Height/2 {
+			return 0, false
+		}
+		if Distance(longitude, latitude, s.Longitude, latitude) > s.Width/2 {
+			return 0, false
+		}
+		return Distance(s.Longitude, s.Latitude, longitude, latitude), true
+	}
+	distance := Distance(s.Longitude, s.Latitude, longitude, latitude)
+	if distance > s.Radius {
+		return 0, false
+	}
+	return distance, true
+}
+
+// boundingBox 返回包含区域的经纬度范围 (minLon, minLat, maxLon, maxLat)
+func (s Shape) boundingBox() (float64, float64, float64, float64) {
+	height, width := s.Radius, s.Radius
+	if s.Box {
+		height, width = s.Height/2, s.Width/2
+	}
+	latDelta := radDeg(height / earthRadiusMeters)
+	longDeltaTop := radDeg(width / earthRadiusMeters / math.Cos(degRad(s.Latitude+latDelta)))
+	longDeltaBottom := radDeg(width / earthRadiusMeters / math.Cos(degRad(s.Latitude-latDelta)))
+	// 南北半球方向相反，取不同的点作为经度的边界
+	if s.Latitude < 0 {
+		return s.Longitude - longDeltaBottom, s.Latitude - latDelta, s.Longitude + longDeltaBottom, s.Latitude + latDelta
+	}
+	return s.Longitude - longDeltaTop, s.Latitude - latDelta, s.Longitude + longDeltaTop, s.Latitude + latDelta
+}
+
+// estimateSteps 根据搜索半径估算 geohash 的精度
+func estimateSteps(rangeMeters, latitude float64) uint {
+	if rangeMeters == 0 {
+		return StepMax
+	}
+	step := 1
+	for rangeMeters < mercatorMax {
+		rangeMeters *= 2
+		step++
+	}
+	step -= 2 // 保证大多数情况下区域能被覆盖
+	// 越靠近两极经度方向的格子越窄
+	if latitude > 66 || latitude < -66 {
+		step--
+		if latitude > 80 || latitude < -80 {
+			step--
+		}
+	}
+	return uint(max(1, min(step, StepMax)))
+}
+
+// moveX 把 hash 沿经度方向移动一格，d 为 1 或 -1
+func (h *hashBits) moveX(d int) {
+	x := h.bits & 0xaaaaaaaaaaaaaaaa
+	y := h.bits & 0x5555555555555555
+	zz := uint64(0x5555555555555555) >> (64 - h.step*2)
+	if d > 0 {
+		x = x + (zz + 1)
+	} else {
+		x = x | zz
+		x = x - (zz + 1)
+	}
+	x &= uint64(0xaaaaaaaaaaaaaaaa) >> (64 - h.step*2)
+	h.bits = x | y
+}
+
+// moveY 把 hash 沿纬度方向移动一格，d 为 1 或 -1
+func (h *hashBits) moveY(d int) {
+	x := h.bits & 0xaaaaaaaaaaaaaaaa
+	y := h.bits & 0x5555555555555555
+	zz := uint64(0xaaaaaaaaaaaaaaaa) >> (64 - h.step*2)
+	if d > 0 {
+		y = y + (zz + 1)
+	} else {
+		y = y | zz
+		y = y - (zz + 1)
+	}
+	y &= uint64(0x5555555555555555) >> (64 - h.step*2)
+	h.bits = x | y
+}
+
+// neighbor 返回相邻的格子，dx/dy 取 -1、0、1
+func (h hashBits) neighbor(dx, dy int) hashBits {
+	if dx != 0 {
+		h.moveX(dx)
+	}
+	if dy != 0 {
+		h.moveY(dy)
+	}
+	return h
+}
+
+// ScoreRange 表示有序集合中的分数区间 [Min, Max)
+type ScoreRange struct {
+	Min, Max uint64
+}
+
+// SearchRanges 返回覆盖搜索区域的分数区间：中心格子及其 8 个相邻格子，
+// 计算方式与 Redis 完全一致，去掉无用的格子以及与前一个相同的格子
+func SearchRanges(s Shape) []ScoreRange {
+	minLon, minLat, maxLon, maxLat := s.boundingBox()
+	radius := s.Radius
+	if s.Box {
+		// 矩形使用中心到角的距离
+		radius = math.Sqrt((s.Width/2)*(s.Width/2) + (s.Height/2)*(s.Height/2))
+	}
+	steps := estimateSteps(radius, s.Latitude)
+
+	hash, _ := encode(wgs84Long, wgs84Lat, s.Longitude, s.Latitude, steps)
+	// 区域靠近格子边缘时估算的精度可能不够，降低一级
+	north := decode(wgs84Long, wgs84Lat, hash.neighbor(0, 1))
+	south := decode(wgs84Long, wgs84Lat, hash.neighbor(0, -1))
+	east := decode(wgs84Long, wgs84Lat, hash.neighbor(1, 0))
+	west := decode(wgs84Long, wgs84Lat, hash.neighbor(-1, 0))
+	if steps > 1 && (north.latitude.max < maxLat || south.latitude.min > minLat ||
+		east.longitude.max < maxLon || west.longitude.min > minLon) {
+		steps--
+		hash, _ = encode(wgs84Long, wgs84Lat, s.Longitude, s.Latitude, steps)
+	}
+	a := decode(wgs84Long, wgs84Lat, hash)
+
+	// 顺序与 Redis 相同：中心、北、南、东、西、东北、西北、东南、西南
+	offsets := [9][2]int{{0, 0}, {0, 1}, {0, -1}, {1, 0}, {-1, 0}, {1, 1}, {-1, 1}, {1, -1}, {-1, -1}}
+	cells := make([]hashBits, len(offsets))
+	for i, o := range offsets {
+		cells[i] = hash.neighbor(o[0], o[1])
+		if steps < 2 || i == 0 {
+			continue
+		}
+		// 排除完全在搜索区域之外的格子
+		if (o[1] < 0 && a.latitude.min < minLat) || (o[1] > 0 && a.latitude.max > maxLat) ||
+			(o[0] < 0 && a.longitude.min < minLon) || (o[0] > 0 && a.longitude.max > maxLon) {
+			cells[i] = hashBits{}
+		}
+	}
+
+	var ranges []ScoreRange
+	last := -1
+	for i, cell := range cells {
+		if cell.isZero() {
+			continue
+		}
+		// 半径很大时相邻格子可能相同，跳过与上一个处理过的格子相同的格子
+		if last > 0 && cell == cells[last] {
+			continue
+		}
+		next := cell
+		next.bits++
+		ranges = append(ranges, ScoreRange{Min: cell.align52(), Max: next.align52()})
+		last = i
+	}
+	return ranges
+}
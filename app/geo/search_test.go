@@ -0,0 +1,117 @@
+package geo
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEstimateSteps(t *testing.T) {
+	tests := []struct {
+		radius, latitude float64
+		want             uint
+	}{
+		{0, 0, StepMax},
+		{200000, 37, 6},
+		{100, 37, 17},
+		{5000000, 0, 2},
+		{30000000, 0, 1},
+		{200000, 70, 5},
+		{200000, -85, 4},
+	}
+	for _, tt := range tests {
+		if got := estimateSteps(tt.radius, tt.latitude); got != tt.want {
+			t.Errorf("estimateSteps(%v, %v) = %d, want %d", tt.radius, tt.latitude, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		shape   Shape
+		want    []ScoreRange
+		members []string // 真实 Redis 对该搜索返回的成员，它们的分数必须落在某个区间内
+	}{
+		{
+			name:  "GEORADIUS Sicily 15 37 200 km",
+			shape: Shape{Longitude: 15, Latitude: 37, Radius: 200000},
+			want: []ScoreRange{
+				{3475556255399936, 3476655767027712},
+				{3478854790283264, 3479954301911040},
+				{3477755278655488, 3478854790283264},
+				{3481053813538816, 3482153325166592},
+			},
+			members: []string{"Palermo", "Catania"},
+		},
+		{
+			name:  "GEOSEARCH Sicily FROMLONLAT 15 37 BYBOX 400 400 km",
+			shape: Shape{Longitude: 15, Latitude: 37, Box: true, Width: 400000, Height: 400000},
+			want: []ScoreRange{
+				{3475556255399936, 3476655767027712},
+				{3478854790283264, 3479954301911040},
+				{3477755278655488, 3478854790283264},
+				{3481053813538816, 3482153325166592},
+			},
+			members: []string{"Palermo", "Catania", "edge1", "edge2"},
+		},
+		{
+			name:  "GEORADIUS Sicily 15 37 100 m",
+			shape: Shape{Longitude: 15, Latitude: 37, Radius: 100},
+			want: []ScoreRange{
+				{3476504601690112, 3476504601952256},
+				{3476504600903680, 3476504601165824},
+				{3476504601165824, 3476504601427968},
+				{3476504600379392, 3476504600641536},
+			},
+		},
+	}
+	points := map[string][2]float64{
+		"Palermo": {13.361389, 38.115556},
+		"Catania": {15.087269, 37.502669},
+		"edge1":   {12.758489, 38.788135},
+		"edge2":   {17.241510, 38.788135},
+	}
+	for _, tt := range tests {
+		got := SearchRanges(tt.shape)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: SearchRanges = %v, want %v", tt.name, got, tt.want)
+		}
+		for _, name := range tt.members {
+			score, _ := Encode(points[name][0], points[name][1])
+			covered := false
+			for _, r := range got {
+				covered = covered || (score >= r.Min && score < r.Max)
+			}
+			if !covered {
+				t.Errorf("%s: %s (%d) is not covered by %v", tt.name, name, score, got)
+			}
+			longitude, latitude := Decode(score)
+			if _, ok := tt.shape.Contains(longitude, latitude); !ok {
+				t.Errorf("%s: %s should be inside the shape", tt.name, name)
+			}
+		}
+	}
+}
+
+func TestContainsDistanceMatchesRedis(t *testing.T) {
+	// GEOSEARCH Sicily FROMLONLAT 15 37 BYBOX 400 400 km ASC WITHDIST
+	shape := Shape{Longitude: 15, Latitude: 37, Box: true, Width: 400000, Height: 400000}
+	tests := []struct {
+		longitude, latitude float64
+		want                string
+	}{
+		{15.087269, 37.502669, "56.4413"},
+		{13.361389, 38.115556, "190.4424"},
+		{17.241510, 38.788135, "279.7403"},
+		{12.758489, 38.788135, "279.7405"},
+	}
+	for _, tt := range tests {
+		score, _ := Encode(tt.longitude, tt.latitude)
+		longitude, latitude := Decode(score)
+		dist, ok := shape.Contains(longitude, latitude)
+		if got := fmt.Sprintf("%.4f", dist/1000); !ok || got != tt.want {
+			t.Errorf("Contains(%v, %v) = %s, %v, want %s", tt.longitude, tt.latitude, got, ok, tt.want)
+		}
+	}
+}
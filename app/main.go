@@ -4,8 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"github.com/codecrafters-io/redis-starter-go/app/commands"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"math"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +21,8 @@ func main() {
 	// 定义 --port 参数，默认 6379
 	port := flag.Int("port", 6379, "port to listen on")
 	replicaof := flag.String("replicaof", "", "Master host and port for replication")
+	flag.Func("proto-max-bulk-len", "max size of a single bulk string request, e.g. 512mb", memoryFlag(&resp.ProtoMaxBulkLen))
+	flag.Func("client-query-buffer-limit", "max size of a client's pending commands, e.g. 1gb", memoryFlag(&resp.ClientQueryBufferLimit))
 	flag.Parse()
 
 	role := "master"
@@ -34,6 +39,7 @@ func main() {
 		_, _ = fmt.Sscan(parts[1], &masterPort)
 	}
 	commands.SetServerRole(role)
+	commands.StartActiveExpireCycle()
 
 	// 如果是副本，启动后台连接主节点的协程
 	if role == "slave" {
@@ -59,3 +65,30 @@ func main() {
 	}
 
 }
+
+// memoryFlag 解析 Redis 风格的内存大小，如 1024、100k、512mb、1gb
+func memoryFlag(dst *int64) func(string) error {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"kb", 1024}, {"mb", 1024 * 1024}, {"gb", 1024 * 1024 * 1024},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000}, {"b", 1},
+	}
+	return func(s string) error {
+		s = strings.ToLower(s)
+		mul := int64(1)
+		for _, u := range units {
+			if strings.HasSuffix(s, u.suffix) {
+				s, mul = strings.TrimSuffix(s, u.suffix), u.size
+				break
+			}
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 || n > math.MaxInt64/mul {
+			return fmt.Errorf("invalid memory size %q", s)
+		}
+		*dst = n * mul
+		return nil
+	}
+}
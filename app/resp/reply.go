@@ -0,0 +1,313 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Reply 是命令的回复，连接按客户端协商的协议版本（2 或 3）编码
+// RESP3 独有的类型在 RESP2 下退化为最接近的类型：映射和集合变为数组，浮点数和原样字符串变为批量字符串
+type Reply interface {
+	// AppendRESP 把回复按协议版本编码后追加到 b
+	AppendRESP(b []byte, protocol int) []byte
+}
+
+// Encode 按协议版本编码回复
+func Encode(r Reply, protocol int) string {
+	return string(r.AppendRESP(nil, protocol))
+}
+
+// SimpleString 是状态回复，如 +OK
+type SimpleString string
+
+// OK 是最常见的状态回复
+const OK = SimpleString("OK")
+
+func (s SimpleString) AppendRESP(b []byte, protocol int) []byte {
+	b = append(b, '+')
+	b = append(b, s...)
+	return append(b, '\r', '\n')
+}
+
+// Error 是带错误码的错误，作为回复时编码为 -CODE message
+type Error struct {
+	Code    string // 错误码，如 ERR、WRONGTYPE
+	Message string
+}
+
+// NewError 创建带错误码的错误
+func NewError(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Code + " " + e.Message
+}
+
+func (e *Error) AppendRESP(b []byte, protocol int) []byte {
+	b = append(b, '-')
+	b = append(b, e.Code...)
+	b = append(b, ' ')
+	// 错误是单行的，换行会破坏协议
+	b = append(b, strings.NewReplacer("\r", " ", "\n", " ").Replace(e.Message)...)
+	return append(b, '\r', '\n')
+}
+
+// ErrorReply 把命令返回的错误转换为错误回复，没有错误码的错误使用 ERR
+func ErrorReply(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Code: "ERR", Message: err.Error()}
+}
+
+// Integer 是整数回复
+type Integer int64
+
+func (i Integer) AppendRESP(b []byte, protocol int) []byte {
+	b = append(b, ':')
+	b = strconv.AppendInt(b, int64(i), 10)
+	return append(b, '\r', '\n')
+}
+
+// BulkString 是批量字符串回复
+type BulkString string
+
+func (s BulkString) AppendRESP(b []byte, protocol int) []byte {
+	return appendBlob(b, '$', string(s))
+}
+
+// Null 是空回复，RESP2 下为 null 批量字符串 $-1
+type Null struct{}
+
+func (Null) AppendRESP(b []byte, protocol int) []byte {
+	if protocol >= 3 {
+		return append(b, '_', '\r', '\n')
+	}
+	return append(b, "$-1\r\n"...)
+}
+
+// NullArray 是空回复，RESP2 下为 null 数组 *-1
+type NullArray struct{}
+
+func (NullArray) AppendRESP(b []byte, protocol int) []byte {
+	if protocol >= 3 {
+		return append(b, '_', '\r', '\n')
+	}
+	return append(b, "*-1\r\n"...)
+}
+
+// Array 是数组回复
+type Array []Reply
+
+func (a Array) AppendRESP(b []byte, protocol int) []byte {
+	return appendAggregate(b, '*', len(a), a, protocol)
+}
+
+// Map 是映射回复，键和值交替排列，RESP2 下为扁平数组
+type Map []Reply
+
+func (m Map) AppendRESP(b []byte, protocol int) []byte {
+	if protocol >= 3 {
+		return appendAggregate(b, '%', len(m)/2, m, protocol)
+	}
+	return appendAggregate(b, '*', len(m), m, protocol)
+}
+
+// Set 是集合回复，RESP2 下为数组
+type Set []Reply
+
+func (s Set) AppendRESP(b []byte, protocol int) []byte {
+	if protocol >= 3 {
+		return appendAggregate(b, '~', len(s), s, protocol)
+	}
+	return appendAggregate(b, '*', len(s), s, protocol)
+}
+
+// Push 是带外推送消息，RESP2 下为数组
+type Push []Reply
+
+func (p Push) AppendRESP(b []byte, protocol int) []byte {
+	if protocol >= 3 {
+		return appendAggregate(b, '>', len(p), p, protocol)
+	}
+	return appendAggregate(b, '*', len(p), p, protocol)
+}
+
+// Double 是浮点数回复，RESP2 下为批量字符串
+type Double float64
+
+func (d Double) AppendRESP(b []byte, protocol int) []byte {
+	if protocol >= 3 {
+		b = append(b, ',')
+		b = append(b, FormatDouble(float64(d))...)
+		return append(b, '\r', '\n')
+	}
+	return appendBlob(b, '$', FormatDouble(float64(d)))
+}
+
+// Boolean 是布尔值回复，RESP2 下为整数 1 或 0
+type Boolean bool
+
+func (v Boolean) AppendRESP(b []byte, protocol int) []byte {
+	switch {
+	case protocol < 3 && bool(v):
+		return append(b, ":1\r\n"...)
+	case protocol < 3:
+		return append(b, ":0\r\n"...)
+	case bool(v):
+		return append(b, "#t\r\n"...)
+	default:
+		return append(b, "#f\r\n"...)
+	}
+}
+
+// BigNumber 是十进制表示的大整数回复，RESP2 下为批量字符串
+type BigNumber string
+
+func (n BigNumber) AppendRESP(b []byte, protocol int) []byte {
+	if protocol >= 3 {
+		b = append(b, '(')
+		b = append(b, n...)
+		return append(b, '\r', '\n')
+	}
+	return appendBlob(b, '$', string(n))
+}
+
+// Verbatim 是带格式的原样字符串回复（如 INFO 的 txt 文本），RESP2 下为批量字符串
+type Verbatim struct {
+	Format string // 三个字符的格式，如 "txt"、"mkd"
+	Text   string
+}
+
+func (v Verbatim) AppendRESP(b []byte, protocol int) []byte {
+	if protocol >= 3 {
+		return appendBlob(b, '=', v.Format+":"+v.Text)
+	}
+	return appendBlob(b, '$', v.Text)
+}
+
+// WithAttributes 给回复附加属性，RESP2 下属性被丢弃
+type WithAttributes struct {
+	Attributes Map
+	Reply      Reply
+}
+
+func (w WithAttributes) AppendRESP(b []byte, protocol int) []byte {
+	if protocol >= 3 {
+		b = appendAggregate(b, '|', len(w.Attributes)/2, w.Attributes, protocol)
+	}
+	return w.Reply.AppendRESP(b, protocol)
+}
+
+// StringArray 构建由批量字符串组成的数组
+func StringArray(items []string) Array {
+	return Array(bulkStrings(items))
+}
+
+// StringMap 构建字段和值交替排列的映射
+func StringMap(pairs []string) Map {
+	return Map(bulkStrings(pairs))
+}
+
+// StringSet 构建由批量字符串组成的集合
+func StringSet(items []string) Set {
+	return Set(bulkStrings(items))
+}
+
+func bulkStrings(items []string) []Reply {
+	replies := make([]Reply, len(items))
+	for i, item := range items {
+		replies[i] = BulkString(item)
+	}
+	return replies
+}
+
+func appendBlob(b []byte, prefix byte, s string) []byte {
+	b = append(b, prefix)
+	b = strconv.AppendInt(b, int64(len(s)), 10)
+	b = append(b, '\r', '\n')
+	b = append(b, s...)
+	return append(b, '\r', '\n')
+}
+
+func appendAggregate(b []byte, prefix byte, n int, elements []Reply, protocol int) []byte {
+	b = append(b, prefix)
+	b = strconv.AppendInt(b, int64(n), 10)
+	b = append(b, '\r', '\n')
+	for _, e := range elements {
+		b = e.AppendRESP(b, protocol)
+	}
+	return b
+}
+
+// FormatDouble 按 Redis 回复双精度数的格式输出：最短往返表示，
+// 指数较大或较小时使用科学计数法（如 1e+20、1.5e-7），无穷大输出 inf/-inf，NaN 输出 nan
+func FormatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	case f == 0:
+		if math.Signbit(f) {
+			return "-0"
+		}
+		return "0"
+	}
+	neg := f < 0
+	// 'e' 格式给出最短的有效数字以及十进制指数
+	e := strconv.FormatFloat(math.Abs(f), 'e', -1, 64)
+	mantissa, expPart, _ := strings.Cut(e, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp10, _ := strconv.Atoi(expPart)
+	ndigits := len(digits)
+	k := exp10 - (ndigits - 1) // 值 = digits * 10^k
+	absExp := exp10
+	if absExp < 0 {
+		absExp = -absExp
+	}
+
+	var sb strings.Builder
+	if neg {
+		sb.WriteByte('-')
+	}
+	switch {
+	case k >= 0 && absExp < ndigits+7:
+		// 整数
+		sb.WriteString(digits)
+		sb.WriteString(strings.Repeat("0", k))
+	case k < 0 && (k > -7 || absExp < 4):
+		// 不使用科学计数法的小数
+		offset := ndigits + k
+		if offset <= 0 {
+			sb.WriteString("0.")
+			sb.WriteString(strings.Repeat("0", -offset))
+			sb.WriteString(digits)
+		} else {
+			sb.WriteString(digits[:offset])
+			sb.WriteByte('.')
+			sb.WriteString(digits[offset:])
+		}
+	default:
+		sb.WriteByte(digits[0])
+		if ndigits > 1 {
+			sb.WriteByte('.')
+			sb.WriteString(digits[1:])
+		}
+		sb.WriteByte('e')
+		if exp10 < 0 {
+			sb.WriteByte('-')
+		} else {
+			sb.WriteByte('+')
+		}
+		sb.WriteString(strconv.Itoa(absExp))
+	}
+	return sb.String()
+}
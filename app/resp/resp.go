@@ -2,63 +2,115 @@ package resp
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"net"
+	"math"
 	"strconv"
 	"strings"
 )
 
+// InlineMaxSize 是单行的最大长度，内联命令和多批量请求的 *、$ 头部行都受此限制，
+// 与 Redis 的 PROTO_INLINE_MAX_SIZE 相同
+const InlineMaxSize = 64 * 1024
+
+// MaxMultibulkLen 是数组、映射等聚合类型的最大元素个数
+const MaxMultibulkLen = math.MaxInt32
+
+// ProtoMaxBulkLen 是单个批量字符串的最大长度（proto-max-bulk-len）
+var ProtoMaxBulkLen int64 = 512 * 1024 * 1024
+
+// ClientQueryBufferLimit 是单个命令以及连接上尚未执行的命令最多占用的字节数（client-query-buffer-limit）
+var ClientQueryBufferLimit int64 = 1024 * 1024 * 1024
+
+// ErrQueryBufferLimit 表示命令超过了 ClientQueryBufferLimit，与 Redis 一样直接关闭连接
+var ErrQueryBufferLimit = errors.New("client reached max query buffer length")
+
+// ProtocolError 表示输入不符合协议，服务端回复 -ERR Protocol error 后关闭连接
+type ProtocolError struct {
+	Reason string
+}
+
+func (e *ProtocolError) Error() string {
+	return "Protocol error: " + e.Reason
+}
+
+func protocolError(format string, args ...any) error {
+	return &ProtocolError{Reason: fmt.Sprintf(format, args...)}
+}
+
 // RESPReader 封装 RESP 解析逻辑
 type RESPReader struct {
-	reader *bufio.Reader
+	reader   *bufio.Reader
+	queryLen int64 // 当前命令已读取的字节数
 }
 
-func NewRESPReader(conn net.Conn) *RESPReader {
+func NewRESPReader(rd io.Reader) *RESPReader {
 	return &RESPReader{
-		reader: bufio.NewReader(conn),
+		reader: bufio.NewReader(rd),
 	}
 }
 
 // Value 表示 RESP 协议中的各种数据类型
 type Value struct {
-	Type    string  // 数据类型: "simple_string", "error", "integer", "bulk_string", "array"
-	String  string  // 用于 simple_string, error, bulk_string
-	Integer int     // 用于 integer
-	Array   []Value // 用于 array
+	// 数据类型: "simple_string", "error", "integer", "bulk_string", "array",
+	// 以及 RESP3 的 "null", "double", "boolean", "big_number", "verbatim_string", "blob_error", "map", "set", "push"
+	Type       string
+	String     string  // 用于 simple_string, error, bulk_string, big_number, verbatim_string, blob_error
+	Format     string  // verbatim_string 的格式，如 "txt"
+	Integer    int     // 用于 integer
+	Double     float64 // 用于 double
+	Bool       bool    // 用于 boolean
+	Array      []Value // 用于 array, set, push；map 中键和值交替排列
+	Attributes []Value // 值前面的属性（|），键和值交替排列
 }
 
 // FormatForDisplay 格式化Value用于调试输出
 func (v Value) FormatForDisplay() string {
 	switch v.Type {
-	case "simple_string":
-		return fmt.Sprintf("(simple_string) %s", v.String)
-	case "error":
-		return fmt.Sprintf("(error) %s", v.String)
+	case "simple_string", "error", "bulk_string", "big_number", "blob_error":
+		return fmt.Sprintf("(%s) %s", v.Type, v.String)
+	case "verbatim_string":
+		return fmt.Sprintf("(verbatim_string) %s:%s", v.Format, v.String)
 	case "integer":
 		return fmt.Sprintf("(integer) %d", v.Integer)
-	case "bulk_string":
-		return fmt.Sprintf("(bulk_string) %s", v.String)
-	case "array":
+	case "double":
+		return fmt.Sprintf("(double) %s", FormatDouble(v.Double))
+	case "boolean":
+		return fmt.Sprintf("(boolean) %t", v.Bool)
+	case "null":
+		return "(null)"
+	case "array", "set", "push":
 		items := make([]string, len(v.Array))
 		for i, item := range v.Array {
 			items[i] = item.FormatForDisplay()
 		}
-		return fmt.Sprintf("(array) [%s]", strings.Join(items, ", "))
+		return fmt.Sprintf("(%s) [%s]", v.Type, strings.Join(items, ", "))
+	case "map":
+		items := make([]string, 0, len(v.Array)/2)
+		for i := 0; i+1 < len(v.Array); i += 2 {
+			items = append(items, v.Array[i].FormatForDisplay()+" => "+v.Array[i+1].FormatForDisplay())
+		}
+		return fmt.Sprintf("(map) {%s}", strings.Join(items, ", "))
 	default:
 		return "(unknown)"
 	}
 }
 
-// Read 通用RESP解析方法，可以处理所有RESP数据类型
+// Read 通用RESP解析方法，可以处理所有 RESP2 和 RESP3 数据类型
 func (r *RESPReader) Read() (Value, error) {
-	line, err := r.reader.ReadString('\n')
+	r.queryLen = 0
+	return r.readValue()
+}
+
+func (r *RESPReader) readValue() (Value, error) {
+	line, err := r.readLine("line")
 	if err != nil {
 		return Value{}, err
 	}
-	line = strings.TrimSuffix(line, "\r\n")
 	if len(line) == 0 {
-		return Value{}, fmt.Errorf("empty response")
+		return Value{}, protocolError("empty line")
 	}
 	switch line[0] {
 	case '+':
@@ -71,125 +123,291 @@ func (r *RESPReader) Read() (Value, error) {
 		// 整数: :1000\r\n
 		intValue, err := strconv.Atoi(line[1:])
 		if err != nil {
-			return Value{}, nil
+			return Value{}, protocolError("invalid integer")
 		}
 		return Value{Type: "integer", Integer: intValue}, nil
 	case '$':
 		// 批量字符串: $5\r\nhello\r\n
-		strLen, err := strconv.Atoi(line[1:])
+		strLen, err := parseLength(line[1:], ProtoMaxBulkLen, true)
 		if err != nil {
-			return Value{}, err
+			return Value{}, protocolError("invalid bulk length")
 		}
 		if strLen == -1 {
 			// 空批量字符串 (Redis NULL)
 			return Value{Type: "bulk_string", String: ""}, nil
 		}
-		// 读取指定长度的字符串
-		data := make([]byte, strLen)
-		_, err = io.ReadFull(r.reader, data)
-		if err != nil {
-			return Value{}, nil
-		}
-		// 消耗尾部的 \r\n
-		_, err = r.reader.Discard(2)
+		data, err := r.readBlob(strLen)
 		if err != nil {
 			return Value{}, err
 		}
-		return Value{Type: "bulk_string", String: string(data)}, nil
+		return Value{Type: "bulk_string", String: data}, nil
 	case '*':
 		// 数组: *2\r\n$5\r\nhello\r\n$5\r\nworld\r\n
-		arrayLen, err := strconv.Atoi(line[1:])
+		arrayLen, err := parseLength(line[1:], MaxMultibulkLen, true)
 		if err != nil {
-			return Value{}, err
+			return Value{}, protocolError("invalid multibulk length")
 		}
 		// 处理空数组
 		if arrayLen == -1 {
 			return Value{Type: "array", Array: nil}, nil
 		}
-		array := make([]Value, arrayLen)
-		for i := 0; i < arrayLen; i++ {
-			val, err := r.Read()
-			if err != nil {
-				return Value{}, err
-			}
-			array[i] = val
+		array, err := r.readElements(arrayLen)
+		if err != nil {
+			return Value{}, err
 		}
 		return Value{Type: "array", Array: array}, nil
+	case '_':
+		// null: _\r\n
+		return Value{Type: "null"}, nil
+	case ',':
+		// 浮点数: ,3.14\r\n，也可以是 inf、-inf 和 nan
+		f, err := strconv.ParseFloat(line[1:], 64)
+		if err != nil {
+			return Value{}, protocolError("invalid double: %q", line[1:])
+		}
+		return Value{Type: "double", Double: f}, nil
+	case '#':
+		// 布尔值: #t\r\n 或 #f\r\n
+		switch line[1:] {
+		case "t":
+			return Value{Type: "boolean", Bool: true}, nil
+		case "f":
+			return Value{Type: "boolean", Bool: false}, nil
+		}
+		return Value{}, protocolError("invalid boolean: %q", line[1:])
+	case '(':
+		// 大整数: (3492890328409238509324850943850943825024385\r\n
+		return Value{Type: "big_number", String: line[1:]}, nil
+	case '!', '=':
+		// 批量错误: !21\r\nSYNTAX invalid syntax\r\n
+		// 原样字符串: =15\r\ntxt:Some string\r\n
+		size, err := parseLength(line[1:], ProtoMaxBulkLen, false)
+		if err != nil {
+			return Value{}, protocolError("invalid bulk length")
+		}
+		data, err := r.readBlob(size)
+		if err != nil {
+			return Value{}, err
+		}
+		if line[0] == '!' {
+			return Value{Type: "blob_error", String: data}, nil
+		}
+		if len(data) < 4 || data[3] != ':' {
+			return Value{}, protocolError("invalid verbatim string: %q", data)
+		}
+		return Value{Type: "verbatim_string", Format: data[:3], String: data[4:]}, nil
+	case '%', '~', '>', '|':
+		// 映射: %2\r\n 后跟 2 对键值；集合: ~2\r\n；推送: >2\r\n；属性: |1\r\n 后跟 1 对键值和被修饰的值
+		n, err := parseLength(line[1:], MaxMultibulkLen/2, false)
+		if err != nil {
+			return Value{}, protocolError("invalid multibulk length")
+		}
+		if line[0] == '%' || line[0] == '|' {
+			n *= 2
+		}
+		elements, err := r.readElements(n)
+		if err != nil {
+			return Value{}, err
+		}
+		switch line[0] {
+		case '%':
+			return Value{Type: "map", Array: elements}, nil
+		case '~':
+			return Value{Type: "set", Array: elements}, nil
+		case '>':
+			return Value{Type: "push", Array: elements}, nil
+		}
+		value, err := r.readValue()
+		if err != nil {
+			return Value{}, err
+		}
+		value.Attributes = elements
+		return value, nil
 	default:
-		return Value{}, fmt.Errorf("unknown RESP type: %s", string(line[0]))
+		return Value{}, protocolError("unknown RESP type: %q", line[0])
 	}
 }
 
-// ReadCommand 读取并解析一个 RESP 命令
-func (r *RESPReader) ReadCommand() ([]string, error) {
-	value, err := r.Read()
+// parseLength 解析 $、* 等头部中的长度，范围是 0 到 max，allowNull 时还允许 -1
+func parseLength(s string, max int64, allowNull bool) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-
-	if value.Type != "array" {
-		return nil, fmt.Errorf("expected RESP array, got %s", value.Type)
+	if (n < 0 && !(allowNull && n == -1)) || n > max {
+		return 0, fmt.Errorf("length out of range: %d", n)
 	}
+	return n, nil
+}
 
-	args := make([]string, len(value.Array))
-	for i, item := range value.Array {
-		if item.Type != "bulk_string" {
-			return nil, fmt.Errorf("expected bulk string in array, got %s", item.Type)
+// readLine 读取一行并去掉行尾的 \r\n，what 用于超过 InlineMaxSize 时的错误信息
+func (r *RESPReader) readLine(what string) (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		if len(line)+len(chunk) > InlineMaxSize {
+			return "", protocolError("too big %s", what)
+		}
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
 		}
-		args[i] = item.String
+		if err != nil {
+			return "", err
+		}
+		if err := r.consume(int64(len(line))); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(string(line), "\r\n"), nil
 	}
+}
 
-	return args, nil
+// consume 累计当前命令读取的字节数，超过 ClientQueryBufferLimit 时返回错误
+func (r *RESPReader) consume(n int64) error {
+	r.queryLen += n
+	if r.queryLen > ClientQueryBufferLimit {
+		return ErrQueryBufferLimit
+	}
+	return nil
 }
 
-// EncodeArray 编码RESP数组
-func EncodeArray(data []interface{}) string {
-	// 返回空数组 *0\r\n，而不是 null
-	result := fmt.Sprintf("*%d\r\n", len(data))
-	for _, item := range data {
-		switch v := item.(type) {
-		case string:
-			result += EncodeBulkString(v)
-		case []interface{}:
-			result += EncodeArray(v)
-		default:
-			result += EncodeBulkString(fmt.Sprintf("%v", v))
+// readBlob 读取指定长度的数据并校验其后的 \r\n
+// 数据按实际收到的字节逐步读入，声明的长度再大也不会一次性分配
+func (r *RESPReader) readBlob(size int64) (string, error) {
+	if err := r.consume(size + 2); err != nil {
+		return "", err
+	}
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r.reader, size); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
 		}
+		return "", err
 	}
-	return result
+	var crlf [2]byte
+	if _, err := io.ReadFull(r.reader, crlf[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	if crlf != [2]byte{'\r', '\n'} {
+		return "", protocolError("expected '\\r\\n' after bulk data")
+	}
+	return data.String(), nil
 }
 
-// EncodeInteger 编码 RESP 整数
-func EncodeInteger(i int) string {
-	return fmt.Sprintf(":%d\r\n", i)
+// readElements 依次读取 n 个值，预分配的容量有上限，避免恶意的长度声明
+func (r *RESPReader) readElements(n int64) ([]Value, error) {
+	elements := make([]Value, 0, min(n, 1024))
+	for range n {
+		val, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, val)
+	}
+	return elements, nil
 }
 
-// EncodeBulkString 编码RESP批量字符串
-func EncodeBulkString(s string) string {
-	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+// ReadCommand 读取并解析一个命令，支持 RESP 数组和内联命令两种格式
+// 输入不符合协议时返回 *ProtocolError
+func (r *RESPReader) ReadCommand() ([]string, error) {
+	for {
+		first, err := r.reader.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		r.queryLen = 0
+		var args []string
+		if first[0] == '*' {
+			args, err = r.readMultibulk()
+		} else {
+			// 内联命令：telnet、nc 或健康检查直接发送 "PING\r\n"
+			args, err = r.readInline()
+		}
+		// 空行和 *0 都被忽略
+		if err != nil || len(args) > 0 {
+			return args, err
+		}
+	}
 }
 
-// EncodeNull 编码RESP null
-func EncodeNull() string {
-	return "$-1\r\n"
+// CommandSize 返回上一次 ReadCommand 读取的命令在连接上占用的字节数
+func (r *RESPReader) CommandSize() int64 {
+	return r.queryLen
 }
 
-// EncodeSimpleString 编码 RESP 简单字符串
-func EncodeSimpleString(s string) string {
-	return fmt.Sprintf("+%s\r\n", s)
+// readMultibulk 读取 *<n>\r\n 后跟 n 个批量字符串的命令
+func (r *RESPReader) readMultibulk() ([]string, error) {
+	line, err := r.readLine("mbulk count string")
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.ParseInt(line[1:], 10, 64)
+	if err != nil || n > MaxMultibulkLen {
+		return nil, protocolError("invalid multibulk length")
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+
+	args := make([]string, 0, min(n, 1024))
+	for range n {
+		line, err := r.readLine("bulk count string")
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			got := byte('\r')
+			if len(line) > 0 {
+				got = line[0]
+			}
+			return nil, protocolError("expected '$', got '%c'", got)
+		}
+		size, err := parseLength(line[1:], ProtoMaxBulkLen, false)
+		if err != nil {
+			return nil, protocolError("invalid bulk length")
+		}
+		arg, err := r.readBlob(size)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
 }
 
-// EncodeError 编码 RESP 错误
-func EncodeError(msg string) string {
-	return fmt.Sprintf("-ERR %s\r\n", msg)
+// readInline 读取一行内联命令并切分参数
+func (r *RESPReader) readInline() ([]string, error) {
+	line, err := r.readLine("inline request")
+	if err != nil {
+		return nil, err
+	}
+	args, err := splitInlineArgs(line)
+	if err != nil {
+		return nil, &ProtocolError{Reason: err.Error()}
+	}
+	return args, nil
 }
 
-// EncodeArrayRaw 用于直接拼接已经编码好的RESP元素（字符串）
-// 用于事务中直接返回已编码的命令执行结果
-func EncodeArrayRaw(elements []interface{}) string {
-	result := fmt.Sprintf("*%d\r\n", len(elements))
-	for _, e := range elements {
-		result += e.(string)
+// ReadRDB 读取 FULLRESYNC 之后主节点发送的 RDB 文件：$<len>\r\n<data>（末尾没有 \r\n）
+func (r *RESPReader) ReadRDB() ([]byte, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 || line[0] != '$' {
+		return nil, fmt.Errorf("expected RDB bulk, got %q", line)
+	}
+	size, err := strconv.Atoi(line[1:])
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid RDB length: %q", line[1:])
+	}
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r.reader, int64(size)); err != nil {
+		return nil, err
 	}
-	return result
+	return data.Bytes(), nil
 }
@@ -0,0 +1,41 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// FuzzReadCommand 检查解析器在任意输入下不会 panic，
+// 并且只返回 EOF、协议错误或查询缓冲区超限
+//
+//	go test ./app/resp -fuzz FuzzReadCommand
+//
+// 种子输入位于 testdata/fuzz/FuzzReadCommand
+func FuzzReadCommand(f *testing.F) {
+	// 调小限制，让变异出的长度更容易越界
+	ProtoMaxBulkLen = 1024
+	ClientQueryBufferLimit = 4096
+
+	readers := map[string]func(r *RESPReader) error{
+		"ReadCommand": func(r *RESPReader) error { _, err := r.ReadCommand(); return err },
+		"Read":        func(r *RESPReader) error { _, err := r.Read(); return err },
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		for name, read := range readers {
+			r := NewRESPReader(bytes.NewReader(input))
+			for {
+				err := read(r)
+				if err == nil {
+					continue
+				}
+				var protoErr *ProtocolError
+				if err != io.EOF && err != io.ErrUnexpectedEOF && err != ErrQueryBufferLimit && !errors.As(err, &protoErr) {
+					t.Fatalf("%s(%q): unexpected error: %v", name, input, err)
+				}
+				break
+			}
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// waiter 表示一个阻塞在一个或多个键上的客户端
+type waiter struct {
+	keys  []string
+	serve func(key string) bool // 在存储的写锁内尝试为该客户端取出数据，成功返回 true
+	done  chan string           // 被服务后收到数据所在的键
+}
+
+// blockedClients 按键记录阻塞的客户端，数据到达时按阻塞的先后顺序（FIFO）直接为客户端取出数据，
+// 保证先阻塞的客户端先被服务，且数据不会在唤醒与重新加锁之间被其他客户端抢走
+// 不自带锁，必须在所属存储的锁保护下使用
+type blockedClients map[string][]*waiter
+
+// block 在 keys 上登记一个阻塞的客户端
+func (b blockedClients) block(keys []string, serve func(key string) bool) *waiter {
+	w := &waiter{keys: keys, serve: serve, done: make(chan string, 1)}
+	for _, key := range keys {
+		b[key] = append(b[key], w)
+	}
+	return w
+}
+
+// unblock 从所有键的等待队列中移除客户端，返回客户端是否仍在等待（尚未被服务）
+func (b blockedClients) unblock(w *waiter) bool {
+	found := false
+	for _, key := range w.keys {
+		waiters := b[key]
+		for i, other := range waiters {
+			if other == w {
+				b[key] = append(waiters[:i:i], waiters[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if len(b[key]) == 0 {
+			delete(b, key)
+		}
+	}
+	return found
+}
+
+// serveKey 在 key 有新数据后调用：按阻塞顺序服务等待的客户端，直到数据被取完
+func (b blockedClients) serveKey(key string) {
+	for len(b[key]) > 0 {
+		w := b[key][0]
+		if !w.serve(key) {
+			return
+		}
+		b.unblock(w)
+		w.done <- key
+	}
+}
+
+// serveEach 在 key 有新数据后调用：依次尝试服务所有等待的客户端
+// 用于数据不会被取走的场景，例如流的新条目可以同时投递给多个消费者组
+// 同一个键在命令中出现多次时客户端在队列中也出现多次，只服务一次
+func (b blockedClients) serveEach(key string) {
+	served := make(map[*waiter]bool)
+	for _, w := range append([]*waiter(nil), b[key]...) {
+		if !served[w] && w.serve(key) {
+			served[w] = true
+			b.unblock(w)
+			w.done <- key
+		}
+	}
+}
+
+// waitUnlockerKey 是 WithWaitUnlocker 在上下文中保存锁使用的键
+type waitUnlockerKey struct{}
+
+// WithWaitUnlocker 返回的上下文让阻塞操作在等待期间释放 l，等待结束后重新获取
+// 用于让持有命令锁的阻塞命令在等待时不妨碍其他命令执行
+func WithWaitUnlocker(ctx context.Context, l sync.Locker) context.Context {
+	return context.WithValue(ctx, waitUnlockerKey{}, l)
+}
+
+// wait 等待客户端被服务、超时或 ctx 被取消，timeout 为 0 表示无限等待
+// 返回数据所在的键，超时返回空字符串，被取消时返回取消原因
+// 调用时不能持有 mu；超时与服务同时发生时以服务为准，数据不会丢失
+func (b blockedClients) wait(ctx context.Context, mu sync.Locker, w *waiter, timeout time.Duration) (string, error) {
+	if l, ok := ctx.Value(waitUnlockerKey{}).(sync.Locker); ok {
+		l.Unlock()
+		defer l.Lock()
+	}
+
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
+	var err error
+	select {
+	case key := <-w.done:
+		return key, nil
+	case <-timeoutCh:
+	case <-ctx.Done():
+		err = context.Cause(ctx)
+	}
+
+	mu.Lock()
+	stillWaiting := b.unblock(w)
+	mu.Unlock()
+	if stillWaiting {
+		return "", err
+	}
+	// 放弃等待之前已经被服务
+	return <-w.done, nil
+}
@@ -0,0 +1,660 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrHashValueNotInteger = errors.New("hash value is not an integer")
+	ErrHashValueNotFloat   = errors.New("hash value is not a float")
+	ErrIncrOverflow        = errors.New("increment or decrement would overflow")
+	ErrIncrNaNOrInfinity   = errors.New("increment would produce NaN or Infinity")
+)
+
+// ExpireCondition 对应 HEXPIRE 等命令的 NX/XX/GT/LT 条件
+type ExpireCondition int
+
+const (
+	ExpireAlways ExpireCondition = iota
+	ExpireNX                     // 仅当字段没有过期时间
+	ExpireXX                     // 仅当字段已有过期时间
+	ExpireGT                     // 仅当新过期时间大于当前过期时间（无过期时间视为无穷大）
+	ExpireLT                     // 仅当新过期时间小于当前过期时间（无过期时间视为无穷大）
+)
+
+// HEXPIRE 系列命令针对每个字段的返回码
+const (
+	FieldNotFound   = -2 // 字段不存在
+	FieldNoExpiry   = -1 // 字段没有过期时间（HTTL/HPERSIST）
+	FieldCondNotMet = 0  // NX/XX/GT/LT 条件不满足
+	FieldExpireSet  = 1  // 设置或移除过期时间成功
+	FieldExpiredNow = 2  // 过期时间已过，字段被立即删除
+)
+
+// FieldSetCondition 对应 HSETEX 的 FNX/FXX 条件
+type FieldSetCondition int
+
+const (
+	SetAlways FieldSetCondition = iota
+	SetFNX                      // 仅当所有字段都不存在
+	SetFXX                      // 仅当所有字段都存在
+)
+
+// FieldTTLMode 决定写入字段时如何处理字段的过期时间
+type FieldTTLMode int
+
+const (
+	TTLClear   FieldTTLMode = iota // 清除过期时间（HSET 的默认行为）
+	TTLKeep                        // 保留原有过期时间（KEEPTTL）
+	TTLSet                         // 设置为指定的过期时间
+	TTLPersist                     // 移除过期时间（HGETEX PERSIST）
+	TTLNone                        // 不修改过期时间（HGETEX 无选项）
+)
+
+// HashOps 定义哈希操作接口
+type HashOps interface {
+	KeyOps
+	SetFields(key string, pairs []string) int
+	SetFieldNX(key, field, value string) bool
+	SetFieldsEx(key string, pairs []string, cond FieldSetCondition, mode FieldTTLMode, expireAtMs int64) bool
+	GetField(key, field string) (string, bool)
+	GetFields(key string, fields []string) ([]string, []bool)
+	GetFieldsEx(key string, fields []string, mode FieldTTLMode, expireAtMs int64) ([]string, []bool, bool)
+	GetDelFields(key string, fields []string) ([]string, []bool)
+	DeleteFields(key string, fields []string) int
+	Length(key string) int
+	FieldExists(key, field string) bool
+	GetAll(key string) []string
+	IncrBy(key, field string, delta int64) (int64, error)
+	IncrByFloat(key, field string, delta float64) (float64, error)
+	RandomFields(key string, count int, allowRepeats bool) []string
+	Scan(key string, cursor uint64, match string, count int) ([]string, uint64)
+	ExpireFields(key string, fields []string, expireAtMs int64, cond ExpireCondition) []int
+	PersistFields(key string, fields []string) []int
+	FieldExpireTimes(key string, fields []string) []int64
+}
+
+// reclaimedFields 记录一次被回收的过期字段
+type reclaimedFields struct {
+	key    string
+	fields []string
+}
+
+// HashStore 实现哈希操作
+type HashStore struct {
+	sync.RWMutex
+	m       map[string]map[string]string
+	expires map[string]map[string]int64 // 设置了过期时间的字段：key -> field -> 过期时间（Unix 毫秒）
+	scans   map[string]*scanIndex       // HSCAN 使用的索引，哈希较大时按需建立
+
+	reclaimed []reclaimedFields                 // 本次持锁期间被删除的过期字段，解锁后通知
+	onReclaim func(key string, fields []string) // 过期字段被删除时的回调（用于向副本传播 HDEL）
+}
+
+func NewHashStore() *HashStore {
+	return &HashStore{
+		m:       make(map[string]map[string]string),
+		expires: make(map[string]map[string]int64),
+		scans:   make(map[string]*scanIndex),
+	}
+}
+
+// SetReclaimHandler 设置过期字段被删除时的回调，回调在释放锁之后调用
+func (s *HashStore) SetReclaimHandler(fn func(key string, fields []string)) {
+	s.Lock()
+	defer s.Unlock()
+	s.onReclaim = fn
+}
+
+// unlock 释放写锁，并通知持锁期间回收的过期字段
+func (s *HashStore) unlock() {
+	reclaimed := s.reclaimed
+	s.reclaimed = nil
+	onReclaim := s.onReclaim
+	s.Unlock()
+	if onReclaim != nil {
+		for _, r := range reclaimed {
+			onReclaim(r.key, r.fields)
+		}
+	}
+}
+
+// Exists 是否存在key
+func (s *HashStore) Exists(key string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.liveLength(key, time.Now().UnixMilli()) > 0
+}
+
+// Delete 删除整个哈希及其字段的过期时间，返回key是否存在
+func (s *HashStore) Delete(key string) bool {
+	s.Lock()
+	defer s.unlock()
+	exists := s.liveLength(key, time.Now().UnixMilli()) > 0
+	delete(s.m, key)
+	delete(s.expires, key)
+	delete(s.scans, key)
+	return exists
+}
+
+// isExpired 判断字段是否已逻辑过期
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *HashStore) isExpired(key, field string, nowMs int64) bool {
+	at, ok := s.expires[key][field]
+	return ok && at <= nowMs
+}
+
+// lookup 获取未过期字段的值
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *HashStore) lookup(key, field string, nowMs int64) (string, bool) {
+	value, exists := s.m[key][field]
+	if !exists || s.isExpired(key, field, nowMs) {
+		return "", false
+	}
+	return value, true
+}
+
+// liveLength 返回未过期字段的数量
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *HashStore) liveLength(key string, nowMs int64) int {
+	n := len(s.m[key])
+	for _, at := range s.expires[key] {
+		if at <= nowMs {
+			n--
+		}
+	}
+	return n
+}
+
+// purgeExpired 删除键中所有已过期的字段并记录下来，哈希为空时删除键
+// 必须在调用者持有写锁的情况下调用
+func (s *HashStore) purgeExpired(key string, nowMs int64) {
+	var fields []string
+	for field, at := range s.expires[key] {
+		if at <= nowMs {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	for _, field := range fields {
+		s.removeField(key, field)
+	}
+	s.reclaimed = append(s.reclaimed, reclaimedFields{key: key, fields: fields})
+}
+
+// removeField 删除字段及其过期时间，哈希为空时删除键
+// 必须在调用者持有写锁的情况下调用
+func (s *HashStore) removeField(key, field string) {
+	hash := s.m[key]
+	delete(hash, field)
+	s.clearExpiry(key, field)
+	s.scans[key].remove(field)
+	if len(hash) == 0 {
+		delete(s.m, key)
+		delete(s.scans, key)
+	}
+}
+
+// setField 写入字段值，返回字段是否为新增
+// 必须在调用者持有写锁的情况下调用
+func (s *HashStore) setField(key string, hash map[string]string, field, value string) bool {
+	_, exists := hash[field]
+	hash[field] = value
+	if !exists {
+		s.scans[key].add(field)
+	}
+	return !exists
+}
+
+// setExpiry 设置字段的过期时间
+// 必须在调用者持有写锁的情况下调用
+func (s *HashStore) setExpiry(key, field string, atMs int64) {
+	fieldExpires, ok := s.expires[key]
+	if !ok {
+		fieldExpires = make(map[string]int64)
+		s.expires[key] = fieldExpires
+	}
+	fieldExpires[field] = atMs
+}
+
+// clearExpiry 清除字段的过期时间
+// 必须在调用者持有写锁的情况下调用
+func (s *HashStore) clearExpiry(key, field string) {
+	if fieldExpires, ok := s.expires[key]; ok {
+		delete(fieldExpires, field)
+		if len(fieldExpires) == 0 {
+			delete(s.expires, key)
+		}
+	}
+}
+
+// getOrCreate 获取哈希（先回收过期字段），不存在则创建
+// 必须在调用者持有写锁的情况下调用
+func (s *HashStore) getOrCreate(key string, nowMs int64) map[string]string {
+	s.purgeExpired(key, nowMs)
+	hash, exists := s.m[key]
+	if !exists {
+		hash = make(map[string]string)
+		s.m[key] = hash
+	}
+	return hash
+}
+
+// dropIfEmpty 删除空哈希
+// 必须在调用者持有写锁的情况下调用
+func (s *HashStore) dropIfEmpty(key string) {
+	if hash, exists := s.m[key]; exists && len(hash) == 0 {
+		delete(s.m, key)
+		delete(s.scans, key)
+	}
+}
+
+// SetFields 设置多个字段（pairs 为 field value 交替排列），返回新增字段数
+// 被覆盖的字段的过期时间会被清除
+func (s *HashStore) SetFields(key string, pairs []string) int {
+	s.Lock()
+	defer s.unlock()
+	hash := s.getOrCreate(key, time.Now().UnixMilli())
+	added := 0
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if s.setField(key, hash, pairs[i], pairs[i+1]) {
+			added++
+		}
+		s.clearExpiry(key, pairs[i])
+	}
+	return added
+}
+
+// SetFieldNX 仅当字段不存在时设置
+func (s *HashStore) SetFieldNX(key, field, value string) bool {
+	s.Lock()
+	defer s.unlock()
+	hash := s.getOrCreate(key, time.Now().UnixMilli())
+	if _, exists := hash[field]; exists {
+		return false
+	}
+	s.setField(key, hash, field, value)
+	return true
+}
+
+// SetFieldsEx 按 FNX/FXX 条件设置多个字段并处理过期时间（HSETEX）
+// 条件不满足时不做任何修改并返回 false
+func (s *HashStore) SetFieldsEx(key string, pairs []string, cond FieldSetCondition, mode FieldTTLMode, expireAtMs int64) bool {
+	s.Lock()
+	defer s.unlock()
+	nowMs := time.Now().UnixMilli()
+	hash := s.getOrCreate(key, nowMs)
+	defer s.dropIfEmpty(key)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		_, exists := hash[pairs[i]]
+		if (cond == SetFNX && exists) || (cond == SetFXX && !exists) {
+			return false
+		}
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		field := pairs[i]
+		if mode == TTLSet && expireAtMs <= nowMs {
+			// 过期时间已过：等同于写入后立即删除
+			s.removeField(key, field)
+			continue
+		}
+		s.setField(key, hash, field, pairs[i+1])
+		switch mode {
+		case TTLSet:
+			s.setExpiry(key, field, expireAtMs)
+		case TTLClear:
+			s.clearExpiry(key, field)
+		}
+	}
+	return true
+}
+
+// GetField 获取字段值
+func (s *HashStore) GetField(key, field string) (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lookup(key, field, time.Now().UnixMilli())
+}
+
+// GetFields 批量获取字段值，第二个返回值标记字段是否存在
+func (s *HashStore) GetFields(key string, fields []string) ([]string, []bool) {
+	s.RLock()
+	defer s.RUnlock()
+	nowMs := time.Now().UnixMilli()
+	values := make([]string, len(fields))
+	found := make([]bool, len(fields))
+	for i, field := range fields {
+		values[i], found[i] = s.lookup(key, field, nowMs)
+	}
+	return values, found
+}
+
+// GetFieldsEx 获取字段值并按 mode 修改存在字段的过期时间（HGETEX）
+// 过期时间已过时字段在返回值之后被删除，此时第三个返回值为 true
+func (s *HashStore) GetFieldsEx(key string, fields []string, mode FieldTTLMode, expireAtMs int64) ([]string, []bool, bool) {
+	s.Lock()
+	defer s.unlock()
+	nowMs := time.Now().UnixMilli()
+	s.purgeExpired(key, nowMs)
+	deleted := mode == TTLSet && expireAtMs <= nowMs
+	values := make([]string, len(fields))
+	found := make([]bool, len(fields))
+	for i, field := range fields {
+		values[i], found[i] = s.lookup(key, field, nowMs)
+		if !found[i] {
+			continue
+		}
+		switch {
+		case deleted:
+			s.removeField(key, field)
+		case mode == TTLSet:
+			s.setExpiry(key, field, expireAtMs)
+		case mode == TTLPersist:
+			s.clearExpiry(key, field)
+		}
+	}
+	return values, found, deleted
+}
+
+// GetDelFields 获取字段值并删除这些字段（HGETDEL）
+func (s *HashStore) GetDelFields(key string, fields []string) ([]string, []bool) {
+	s.Lock()
+	defer s.unlock()
+	s.purgeExpired(key, time.Now().UnixMilli())
+	values := make([]string, len(fields))
+	found := make([]bool, len(fields))
+	for i, field := range fields {
+		values[i], found[i] = s.m[key][field]
+		if found[i] {
+			s.removeField(key, field)
+		}
+	}
+	return values, found
+}
+
+// DeleteFields 删除字段，返回实际删除的数量；哈希为空时删除键
+func (s *HashStore) DeleteFields(key string, fields []string) int {
+	s.Lock()
+	defer s.unlock()
+	s.purgeExpired(key, time.Now().UnixMilli())
+	hash, exists := s.m[key]
+	if !exists {
+		return 0
+	}
+	deleted := 0
+	for _, field := range fields {
+		if _, ok := hash[field]; ok {
+			s.removeField(key, field)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Length 返回字段数量
+func (s *HashStore) Length(key string) int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.liveLength(key, time.Now().UnixMilli())
+}
+
+// FieldExists 检查字段是否存在
+func (s *HashStore) FieldExists(key, field string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	_, exists := s.lookup(key, field, time.Now().UnixMilli())
+	return exists
+}
+
+// GetAll 返回所有字段和值，按 field value 交替排列
+func (s *HashStore) GetAll(key string) []string {
+	s.RLock()
+	defer s.RUnlock()
+	nowMs := time.Now().UnixMilli()
+	hash := s.m[key]
+	result := make([]string, 0, len(hash)*2)
+	for field, value := range hash {
+		if !s.isExpired(key, field, nowMs) {
+			result = append(result, field, value)
+		}
+	}
+	return result
+}
+
+// IncrBy 将字段的整数值增加 delta，字段不存在时视为 0；字段的过期时间保持不变
+func (s *HashStore) IncrBy(key, field string, delta int64) (int64, error) {
+	s.Lock()
+	defer s.unlock()
+	hash := s.getOrCreate(key, time.Now().UnixMilli())
+	defer s.dropIfEmpty(key)
+	var current int64
+	if value, exists := hash[field]; exists {
+		var err error
+		current, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, ErrHashValueNotInteger
+		}
+	}
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, ErrIncrOverflow
+	}
+	current += delta
+	s.setField(key, hash, field, strconv.FormatInt(current, 10))
+	return current, nil
+}
+
+// IncrByFloat 将字段的浮点值增加 delta，字段不存在时视为 0；字段的过期时间保持不变
+func (s *HashStore) IncrByFloat(key, field string, delta float64) (float64, error) {
+	s.Lock()
+	defer s.unlock()
+	hash := s.getOrCreate(key, time.Now().UnixMilli())
+	defer s.dropIfEmpty(key)
+	var current float64
+	if value, exists := hash[field]; exists {
+		var err error
+		current, err = strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(current) || math.IsInf(current, 0) {
+			return 0, ErrHashValueNotFloat
+		}
+	}
+	current += delta
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return 0, ErrIncrNaNOrInfinity
+	}
+	s.setField(key, hash, field, FormatFloat(current))
+	return current, nil
+}
+
+// liveFields 返回所有未过期的字段名
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *HashStore) liveFields(key string, nowMs int64) []string {
+	hash := s.m[key]
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		if !s.isExpired(key, field, nowMs) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// RandomFields 随机返回字段（field value 交替排列）
+// allowRepeats 为 false 时返回至多 count 个不重复字段，否则返回恰好 count 个（至多 MaxRandomRepeats 个）可能重复的字段
+// 索引按需建立，因此持有写锁
+func (s *HashStore) RandomFields(key string, count int, allowRepeats bool) []string {
+	s.Lock()
+	defer s.unlock()
+	nowMs := time.Now().UnixMilli()
+	live := s.liveLength(key, nowMs)
+	if live == 0 || count <= 0 {
+		return []string{}
+	}
+	hash := s.m[key]
+	list := func() []string { return s.liveFields(key, nowMs) }
+	index := func() *scanIndex { return s.index(key) }
+	if live < len(hash) {
+		// 还有已过期但尚未回收的字段，索引中的排名不再对应存活字段，只能复制存活字段
+		index = nil
+	}
+	fields := randomMembers(live, count, allowRepeats, list, index)
+	result := make([]string, 0, len(fields)*2)
+	for _, field := range fields {
+		result = append(result, field, hash[field])
+	}
+	return result
+}
+
+// index 返回键的 HSCAN 索引，尚未建立时建立
+// 索引包含已过期但尚未回收的字段，与 s.m 保持一致
+// 必须在调用者持有写锁的情况下调用
+func (s *HashStore) index(key string) *scanIndex {
+	idx, ok := s.scans[key]
+	if !ok {
+		fields := make([]string, 0, len(s.m[key]))
+		for field := range s.m[key] {
+			fields = append(fields, field)
+		}
+		idx = newScanIndex(fields)
+		s.scans[key] = idx
+	}
+	return idx
+}
+
+// Scan 按游标遍历字段，返回 field value 交替排列的结果和下一个游标
+// 索引按需建立，因此持有写锁
+func (s *HashStore) Scan(key string, cursor uint64, match string, count int) ([]string, uint64) {
+	s.Lock()
+	defer s.unlock()
+	nowMs := time.Now().UnixMilli()
+	var matched []string
+	var next uint64
+	if s.liveLength(key, nowMs) <= scanSmallThreshold {
+		matched = scanAll(s.liveFields(key, nowMs), match)
+	} else {
+		// 索引中已过期但尚未回收的字段由 live 跳过
+		live := func(field string) bool { return !s.isExpired(key, field, nowMs) }
+		matched, next = s.index(key).scan(cursor, match, count, live)
+	}
+	hash := s.m[key]
+	result := make([]string, 0, len(matched)*2)
+	for _, field := range matched {
+		result = append(result, field, hash[field])
+	}
+	return result, next
+}
+
+// ExpireFields 按条件设置字段的过期时间，返回每个字段的结果码
+func (s *HashStore) ExpireFields(key string, fields []string, expireAtMs int64, cond ExpireCondition) []int {
+	s.Lock()
+	defer s.unlock()
+	nowMs := time.Now().UnixMilli()
+	s.purgeExpired(key, nowMs)
+	results := make([]int, len(fields))
+	for i, field := range fields {
+		if _, exists := s.m[key][field]; !exists {
+			results[i] = FieldNotFound
+			continue
+		}
+		current, hasExpiry := s.expires[key][field]
+		ok := true
+		switch cond {
+		case ExpireNX:
+			ok = !hasExpiry
+		case ExpireXX:
+			ok = hasExpiry
+		case ExpireGT:
+			ok = hasExpiry && expireAtMs > current
+		case ExpireLT:
+			ok = !hasExpiry || expireAtMs < current
+		}
+		switch {
+		case !ok:
+			results[i] = FieldCondNotMet
+		case expireAtMs <= nowMs:
+			s.removeField(key, field)
+			results[i] = FieldExpiredNow
+		default:
+			s.setExpiry(key, field, expireAtMs)
+			results[i] = FieldExpireSet
+		}
+	}
+	return results
+}
+
+// PersistFields 移除字段的过期时间，返回每个字段的结果码
+func (s *HashStore) PersistFields(key string, fields []string) []int {
+	s.Lock()
+	defer s.unlock()
+	s.purgeExpired(key, time.Now().UnixMilli())
+	results := make([]int, len(fields))
+	for i, field := range fields {
+		if _, exists := s.m[key][field]; !exists {
+			results[i] = FieldNotFound
+			continue
+		}
+		if _, hasExpiry := s.expires[key][field]; !hasExpiry {
+			results[i] = FieldNoExpiry
+			continue
+		}
+		s.clearExpiry(key, field)
+		results[i] = FieldExpireSet
+	}
+	return results
+}
+
+// FieldExpireTimes 返回每个字段的过期时间（Unix 毫秒）
+// 字段不存在返回 -2，没有过期时间返回 -1
+func (s *HashStore) FieldExpireTimes(key string, fields []string) []int64 {
+	s.RLock()
+	defer s.RUnlock()
+	nowMs := time.Now().UnixMilli()
+	results := make([]int64, len(fields))
+	for i, field := range fields {
+		if _, exists := s.lookup(key, field, nowMs); !exists {
+			results[i] = FieldNotFound
+			continue
+		}
+		at, hasExpiry := s.expires[key][field]
+		if !hasExpiry {
+			results[i] = FieldNoExpiry
+			continue
+		}
+		results[i] = at
+	}
+	return results
+}
+
+// ActiveExpire 主动回收已过期的字段，返回检查的键数和其中有字段过期的键数
+// 最多检查 maxKeys 个设置了过期字段的键，回收结果通过 reclaim 回调通知
+func (s *HashStore) ActiveExpire(maxKeys int) (int, int) {
+	s.Lock()
+	defer s.unlock()
+	nowMs := time.Now().UnixMilli()
+	before := len(s.reclaimed)
+	checked := 0
+	for key := range s.expires {
+		if checked >= maxKeys {
+			break
+		}
+		checked++
+		s.purgeExpired(key, nowMs)
+	}
+	return checked, len(s.reclaimed) - before
+}
+
+// FormatFloat 按 Redis 的方式格式化浮点数（不使用科学计数法，去掉多余的 0）
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -8,26 +9,27 @@ import (
 
 // ListOps 定义列表操作接口
 type ListOps interface {
-	Exists(key string) bool
+	KeyOps
 	AppendList(key string, elements []string) (int, error)
 	PrependList(key string, elements []string) (int, error)
 	GetListRange(key string, start, stop int) ([]string, error)
 	GetListLength(key string) (int, error)
 	LPopElement(key string, count int) ([]string, bool, error)
-	BLPopElement(key string, timeout time.Duration) (string, bool, error)
+	BLPopElement(ctx context.Context, keys []string, timeout time.Duration) (string, string, bool, error)
+	StoreList(key string, elements []string) int
 }
 
 // ListStore 实现列表操作
 type ListStore struct {
 	sync.RWMutex
 	m       map[string][]string
-	waiters map[string][]chan struct{} // 每个键对应的等待队列（通道切片）
+	blocked blockedClients // 阻塞在 BLPOP 上的客户端
 }
 
 func NewListStore() *ListStore {
 	return &ListStore{
 		m:       make(map[string][]string),
-		waiters: make(map[string][]chan struct{}),
+		blocked: make(blockedClients),
 	}
 }
 
@@ -39,6 +41,15 @@ func (s *ListStore) Exists(key string) bool {
 	return exists
 }
 
+// Delete 删除key，返回key是否存在
+func (s *ListStore) Delete(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, exists := s.m[key]
+	delete(s.m, key)
+	return exists
+}
+
 // checkList 检查键是否存在、类型是否正确以及列表是否为空
 // 必须在调用者持有读锁或写锁的情况下调用
 func (s *ListStore) checkList(key string) ([]string, bool, error) {
@@ -63,16 +74,23 @@ func (s *ListStore) AppendList(key string, elements []string) (int, error) {
 		list = append(list, elements...)
 		s.m[key] = list
 	}
-	// 唤醒该键的第一个等待者
-	if waiters, ok := s.waiters[key]; ok && len(waiters) > 0 {
-		waiter := waiters[0]         // 获取最先等待的客户端
-		s.waiters[key] = waiters[1:] // 移除已唤醒的客户端
-		if len(s.waiters[key]) == 0 {
-			delete(s.waiters, key)
-		}
-		close(waiter) // 唤醒客户端（非阻塞）
+	// 返回写入后的长度，不受随后被阻塞客户端弹出的影响
+	length := len(s.m[key])
+	s.blocked.serveKey(key)
+	return length, nil
+}
+
+// StoreList 用给定元素替换列表的全部内容，元素为空时删除键；返回列表长度
+func (s *ListStore) StoreList(key string, elements []string) int {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.m, key)
+	if len(elements) == 0 {
+		return 0
 	}
-	return len(s.m[key]), nil
+	s.m[key] = elements
+	s.blocked.serveKey(key)
+	return len(elements)
 }
 
 // GetListRange 获取列表指定范围的元素
@@ -130,16 +148,10 @@ func (s *ListStore) PrependList(key string, elements []string) (int, error) {
 	} else {
 		s.m[key] = append(newList, list...)
 	}
-	// 唤醒该键的第一个等待者
-	if waiters, ok := s.waiters[key]; ok && len(waiters) > 0 {
-		waiter := waiters[0]         // 获取最先等待的客户端
-		s.waiters[key] = waiters[1:] // 移除已唤醒的客户端
-		if len(s.waiters[key]) == 0 {
-			delete(s.waiters, key)
-		}
-		close(waiter) // 唤醒客户端（非阻塞）
-	}
-	return len(s.m[key]), nil
+	// 返回写入后的长度，不受随后被阻塞客户端弹出的影响
+	length := len(s.m[key])
+	s.blocked.serveKey(key)
+	return length, nil
 }
 
 // GetListLength 获取对应列表的长度
@@ -184,68 +196,44 @@ func (s *ListStore) LPopElement(key string, count int) ([]string, bool, error) {
 	return popped, true, nil
 }
 
-// BLPopElement 阻塞弹出列表头部元素，timeout 0 表示无限阻塞
-func (s *ListStore) BLPopElement(key string, timeout time.Duration) (string, bool, error) {
+// popFront 弹出列表头部元素，列表为空时删除键
+// 必须在调用者持有写锁的情况下调用
+func (s *ListStore) popFront(key string) (string, bool) {
+	list, ok := s.m[key]
+	if !ok || len(list) == 0 {
+		return "", false
+	}
+	elem := list[0]
+	if len(list) == 1 {
+		delete(s.m, key)
+	} else {
+		s.m[key] = list[1:]
+	}
+	return elem, true
+}
+
+// BLPopElement 从第一个非空列表的头部弹出元素，所有列表都为空时阻塞，timeout 0 表示无限阻塞
+// 返回弹出元素所在的键和元素，超时返回 ok=false
+// ctx 被取消时（连接断开或 CLIENT UNBLOCK）放弃等待并返回取消原因；取消前已被服务时仍返回弹出的元素
+func (s *ListStore) BLPopElement(ctx context.Context, keys []string, timeout time.Duration) (string, string, bool, error) {
 	s.Lock()
-	// 快速检查：如果列表有元素，直接弹出
-	if list, ok := s.m[key]; ok && len(list) > 0 {
-		elem := list[0]
-		if len(list) == 1 {
-			delete(s.m, key)
-		} else {
-			s.m[key] = list[1:]
+	for _, key := range keys {
+		if elem, ok := s.popFront(key); ok {
+			s.Unlock()
+			return key, elem, true, nil
 		}
-		s.Unlock()
-		return elem, true, nil
 	}
-
-	// 创建等待通道并加入队列
-	ch := make(chan struct{})
-	s.waiters[key] = append(s.waiters[key], ch)
+	var elem string
+	w := s.blocked.block(keys, func(key string) bool {
+		var ok bool
+		elem, ok = s.popFront(key)
+		return ok
+	})
 	s.Unlock()
 
-	// 处理超时
-	var timeoutCh <-chan time.Time
-	if timeout > 0 {
-		timeoutCh = time.After(timeout)
-	} else if timeout == 0 {
-		// 0 表示无限阻塞
-		timeoutCh = nil
-	}
-
-	select {
-	case <-ch: // 被唤醒
-		s.Lock()
-		defer s.Unlock()
-		// 再次检查列表状态
-		list, ok := s.m[key]
-		if !ok || len(list) == 0 {
-			return "", false, nil // 元素已被其他消费者取走
-		}
-		elem := list[0]
-		if len(list) == 1 {
-			delete(s.m, key)
-		} else {
-			s.m[key] = list[1:]
-		}
-		return elem, true, nil
-	case <-timeoutCh: // 超时
-		s.Lock()
-		defer s.Unlock()
-		// 从等待队列中移除自己
-		if waiters, ok := s.waiters[key]; ok {
-			for i, waiter := range waiters {
-				if waiter == ch {
-					// 从切片中移除通道
-					s.waiters[key] = append(waiters[:i], waiters[i+1:]...)
-					// 清理空队列
-					if len(s.waiters[key]) == 0 {
-						delete(s.waiters, key)
-					}
-					break
-				}
-			}
-		}
-		return "", false, nil
+	key, err := s.blocked.wait(ctx, s, w, timeout)
+	if err != nil || key == "" {
+		return "", "", false, err
 	}
+	return key, elem, true, nil
 }
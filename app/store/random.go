@@ -0,0 +1,52 @@
+package store
+
+import "math/rand"
+
+// MaxRandomRepeats 是允许重复时一次最多抽取的元素数
+// Redis 只要求 count 不超过 LONG_MAX/2，但这里的回复要先完整地构造在内存中，过大的 count 会耗尽内存
+const MaxRandomRepeats = 1 << 20
+
+// randomIndexes 随机选出 [0, n) 中的下标，由调用者按下标取元素，n 必须大于 0
+// allowRepeats 为 true 时返回 min(count, MaxRandomRepeats) 个可能重复的下标，否则返回 min(count, n) 个不重复的下标
+// 不重复且 count 远小于 n 时与 Redis 一样反复抽取并去重，耗时只与 count 有关
+func randomIndexes(n, count int, allowRepeats bool) []int {
+	if allowRepeats {
+		indexes := make([]int, min(count, MaxRandomRepeats))
+		for i := range indexes {
+			indexes[i] = rand.Intn(n)
+		}
+		return indexes
+	}
+	if count > n/3 {
+		return rand.Perm(n)[:min(count, n)]
+	}
+	seen := make(map[int]struct{}, count)
+	indexes := make([]int, 0, count)
+	for len(indexes) < count {
+		i := rand.Intn(n)
+		if _, exists := seen[i]; !exists {
+			seen[i] = struct{}{}
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// randomMembers 从 size 个元素中随机抽取，count 和 allowRepeats 的含义同 randomIndexes
+// 小集合或 index 为 nil 时在 list 返回的元素中选取；否则通过 index 返回的 SCAN 索引按排名选取，不需要复制全部元素
+func randomMembers(size, count int, allowRepeats bool, list func() []string, index func() *scanIndex) []string {
+	indexes := randomIndexes(size, count, allowRepeats)
+	result := make([]string, len(indexes))
+	if size <= scanSmallThreshold || index == nil {
+		members := list()
+		for i, j := range indexes {
+			result[i] = members[j]
+		}
+		return result
+	}
+	idx := index()
+	for i, j := range indexes {
+		result[i] = idx.at(j)
+	}
+	return result
+}
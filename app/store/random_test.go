@@ -0,0 +1,32 @@
+package store
+
+import "testing"
+
+func TestRandomIndexes(t *testing.T) {
+	tests := []struct {
+		n, count     int
+		allowRepeats bool
+		want         int
+	}{
+		{10, 3, false, 3},
+		{10, 4, false, 4},
+		{10, 10, false, 10},
+		{10, 100, false, 10},
+		{1000000, 5, false, 5},
+		{3, 50, true, 50},
+		{3, MaxRandomRepeats + 1, true, MaxRandomRepeats},
+	}
+	for _, tt := range tests {
+		indexes := randomIndexes(tt.n, tt.count, tt.allowRepeats)
+		if len(indexes) != tt.want {
+			t.Errorf("randomIndexes(%d, %d, %v) returned %d indexes, want %d", tt.n, tt.count, tt.allowRepeats, len(indexes), tt.want)
+		}
+		seen := map[int]bool{}
+		for _, i := range indexes {
+			if i < 0 || i >= tt.n || (!tt.allowRepeats && seen[i]) {
+				t.Fatalf("randomIndexes(%d, %d, %v) returned bad index %d in %v", tt.n, tt.count, tt.allowRepeats, i, indexes)
+			}
+			seen[i] = true
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// scanOrder 返回元素在 SCAN 游标空间中的位置（从 1 开始，0 保留给起始/结束游标）
+// 位置只取决于元素本身，因此在两次 SCAN 调用之间增删其他元素不会影响已有元素的位置
+func scanOrder(member string) uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(member))
+	return uint64(h.Sum32()) + 1
+}
+
+// scanSmallThreshold 以内的集合与 Redis 的 listpack/intset 编码一样，一次 SCAN 返回全部元素，游标为 0
+const scanSmallThreshold = 128
+
+// scanAll 一次返回小集合中所有匹配的元素
+func scanAll(members []string, match string) []string {
+	if match == "" {
+		return members
+	}
+	result := make([]string, 0, len(members))
+	for _, m := range members {
+		if MatchPattern(match, m) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// scanIndex 按游标位置（scanOrder 相同时按元素）排列元素，每次 SCAN 只需访问约 COUNT 个元素
+// 较大的集合第一次被 SCAN 时建立，此后随元素增删同步更新；nil 表示尚未建立，增删时直接忽略
+type scanIndex struct {
+	zsl *skiplist
+}
+
+func newScanIndex(members []string) *scanIndex {
+	idx := &scanIndex{zsl: newSkiplist()}
+	for _, m := range members {
+		idx.add(m)
+	}
+	return idx
+}
+
+// add 加入新元素，调用者需保证元素原来不存在
+func (idx *scanIndex) add(member string) {
+	if idx != nil {
+		idx.zsl.insert(float64(scanOrder(member)), member)
+	}
+}
+
+func (idx *scanIndex) remove(member string) {
+	if idx != nil {
+		idx.zsl.delete(float64(scanOrder(member)), member)
+	}
+}
+
+// at 返回按游标位置排列的第 i 个元素（从 0 开始），供随机抽取元素时按下标访问
+func (idx *scanIndex) at(i int) string {
+	return idx.zsl.byRank(i + 1).member
+}
+
+// scan 实现 *SCAN 命令的游标遍历
+// 返回游标位置 >= cursor 的前 count 个元素中匹配的元素（同一位置的元素总是一起返回），以及下一次调用的游标
+// live 不为 nil 时跳过它返回 false 的元素（如已过期的哈希字段）
+// 在整个遍历期间一直存在的元素保证至少被返回一次
+func (idx *scanIndex) scan(cursor uint64, match string, count int, live func(member string) bool) ([]string, uint64) {
+	if count < 1 {
+		count = 10
+	}
+	result := make([]string, 0, count)
+	visited := 0
+	var prev *skiplistNode
+	for x := idx.zsl.firstInRange(ScoreRange{Min: float64(cursor), Max: math.Inf(1)}); x != nil; prev, x = x, x.level[0].forward {
+		// COUNT 是访问元素数而不是返回元素数；同一位置的元素不能拆开
+		if visited >= count && x.score != prev.score {
+			return result, uint64(x.score)
+		}
+		visited++
+		if (live == nil || live(x.member)) && (match == "" || MatchPattern(match, x.member)) {
+			result = append(result, x.member)
+		}
+	}
+	return result, 0
+}
+
+// MatchPattern 按 Redis 的 glob 规则匹配字符串
+// 支持 *、?、[abc]、[^abc]、[a-z] 以及反斜杠转义
+func MatchPattern(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// 合并连续的 *
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if MatchPattern(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			pattern = pattern[1:]
+			negate := len(pattern) > 0 && pattern[0] == '^'
+			if negate {
+				pattern = pattern[1:]
+			}
+			matched := false
+			for len(pattern) > 0 && pattern[0] != ']' {
+				switch {
+				case pattern[0] == '\\' && len(pattern) >= 2:
+					if pattern[1] == s[0] {
+						matched = true
+					}
+					pattern = pattern[2:]
+				case len(pattern) >= 3 && pattern[1] == '-' && pattern[2] != ']':
+					lo, hi := pattern[0], pattern[2]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					if s[0] >= lo && s[0] <= hi {
+						matched = true
+					}
+					pattern = pattern[3:]
+				default:
+					if pattern[0] == s[0] {
+						matched = true
+					}
+					pattern = pattern[1:]
+				}
+			}
+			if len(pattern) > 0 {
+				pattern = pattern[1:] // 跳过 ]
+			}
+			if matched == negate {
+				return false
+			}
+			s = s[1:]
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
@@ -0,0 +1,475 @@
+package store
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// setMaxIntsetEntries 整数集合编码的最大元素数，超过后转换为哈希表编码（与 Redis 默认值相同）
+const setMaxIntsetEntries = 512
+
+// SetOps 定义集合操作接口
+type SetOps interface {
+	KeyOps
+	Add(key string, members []string) int
+	Remove(key string, members []string) int
+	Members(key string) []string
+	IsMember(key, member string) bool
+	MembersExist(key string, members []string) []bool
+	Card(key string) int
+	Pop(key string, count int) []string
+	RandomMembers(key string, count int, allowRepeats bool) []string
+	Move(src, dst, member string) bool
+	Scan(key string, cursor uint64, match string, count int) ([]string, uint64)
+	Inter(keys []string) []string
+	InterCard(keys []string, limit int) int
+	Union(keys []string) []string
+	Diff(keys []string) []string
+	StoreMembers(key string, members []string) int
+}
+
+// setObject 表示一个集合
+// 只包含整数且元素不多时使用有序整数数组（intset）编码，否则使用哈希表编码
+type setObject struct {
+	ints    []int64             // intset 编码：升序排列的整数，members 为 nil 时使用
+	members map[string]struct{} // 哈希表编码
+	scan    *scanIndex          // SSCAN 使用的索引，集合较大时按需建立
+}
+
+// parseIntsetMember 判断元素能否以整数形式保存（必须是规范的十进制整数表示）
+func parseIntsetMember(member string) (int64, bool) {
+	v, err := strconv.ParseInt(member, 10, 64)
+	if err != nil || strconv.FormatInt(v, 10) != member {
+		return 0, false
+	}
+	return v, true
+}
+
+func (o *setObject) isIntset() bool {
+	return o.members == nil
+}
+
+// convertToHashtable 将 intset 编码转换为哈希表编码
+func (o *setObject) convertToHashtable() {
+	o.members = make(map[string]struct{}, len(o.ints)+1)
+	for _, v := range o.ints {
+		o.members[strconv.FormatInt(v, 10)] = struct{}{}
+	}
+	o.ints = nil
+}
+
+// searchInt 在 intset 中二分查找，返回插入位置以及是否存在
+func (o *setObject) searchInt(v int64) (int, bool) {
+	i := sort.Search(len(o.ints), func(i int) bool { return o.ints[i] >= v })
+	return i, i < len(o.ints) && o.ints[i] == v
+}
+
+func (o *setObject) add(member string) bool {
+	if o.isIntset() {
+		if v, ok := parseIntsetMember(member); ok && len(o.ints) < setMaxIntsetEntries {
+			i, exists := o.searchInt(v)
+			if exists {
+				return false
+			}
+			o.ints = append(o.ints, 0)
+			copy(o.ints[i+1:], o.ints[i:])
+			o.ints[i] = v
+			o.scan.add(member)
+			return true
+		}
+		if o.contains(member) {
+			return false
+		}
+		o.convertToHashtable()
+	}
+	if _, exists := o.members[member]; exists {
+		return false
+	}
+	o.members[member] = struct{}{}
+	o.scan.add(member)
+	return true
+}
+
+func (o *setObject) remove(member string) bool {
+	if o.isIntset() {
+		v, ok := parseIntsetMember(member)
+		if !ok {
+			return false
+		}
+		i, exists := o.searchInt(v)
+		if !exists {
+			return false
+		}
+		o.ints = append(o.ints[:i], o.ints[i+1:]...)
+		o.scan.remove(member)
+		return true
+	}
+	if _, exists := o.members[member]; !exists {
+		return false
+	}
+	delete(o.members, member)
+	o.scan.remove(member)
+	return true
+}
+
+func (o *setObject) contains(member string) bool {
+	if o.isIntset() {
+		v, ok := parseIntsetMember(member)
+		if !ok {
+			return false
+		}
+		_, exists := o.searchInt(v)
+		return exists
+	}
+	_, exists := o.members[member]
+	return exists
+}
+
+func (o *setObject) size() int {
+	if o.isIntset() {
+		return len(o.ints)
+	}
+	return len(o.members)
+}
+
+// index 返回 SSCAN 索引，尚未建立时建立
+func (o *setObject) index() *scanIndex {
+	if o.scan == nil {
+		o.scan = newScanIndex(o.list())
+	}
+	return o.scan
+}
+
+// random 随机抽取元素，count 和 allowRepeats 的含义同 randomIndexes
+// intset 编码的元素不多，直接复制；哈希表编码通过 SSCAN 索引按排名抽取
+func (o *setObject) random(count int, allowRepeats bool) []string {
+	index := o.index
+	if o.isIntset() {
+		index = nil
+	}
+	return randomMembers(o.size(), count, allowRepeats, o.list, index)
+}
+
+// list 返回所有元素，intset 编码时按数值升序
+func (o *setObject) list() []string {
+	result := make([]string, 0, o.size())
+	if o.isIntset() {
+		for _, v := range o.ints {
+			result = append(result, strconv.FormatInt(v, 10))
+		}
+		return result
+	}
+	for member := range o.members {
+		result = append(result, member)
+	}
+	return result
+}
+
+// SetStore 实现集合操作
+type SetStore struct {
+	sync.RWMutex
+	m map[string]*setObject
+}
+
+func NewSetStore() *SetStore {
+	return &SetStore{
+		m: make(map[string]*setObject),
+	}
+}
+
+// Exists 是否存在key
+func (s *SetStore) Exists(key string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	_, exists := s.m[key]
+	return exists
+}
+
+// Delete 删除集合，返回key是否存在
+func (s *SetStore) Delete(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, exists := s.m[key]
+	delete(s.m, key)
+	return exists
+}
+
+// Add 添加元素，返回新增的数量
+func (s *SetStore) Add(key string, members []string) int {
+	s.Lock()
+	defer s.Unlock()
+	set, exists := s.m[key]
+	if !exists {
+		set = &setObject{}
+		s.m[key] = set
+	}
+	added := 0
+	for _, member := range members {
+		if set.add(member) {
+			added++
+		}
+	}
+	return added
+}
+
+// Remove 删除元素，返回实际删除的数量；集合为空时删除键
+func (s *SetStore) Remove(key string, members []string) int {
+	s.Lock()
+	defer s.Unlock()
+	set, exists := s.m[key]
+	if !exists {
+		return 0
+	}
+	removed := 0
+	for _, member := range members {
+		if set.remove(member) {
+			removed++
+		}
+	}
+	if set.size() == 0 {
+		delete(s.m, key)
+	}
+	return removed
+}
+
+// Members 返回所有元素
+func (s *SetStore) Members(key string) []string {
+	s.RLock()
+	defer s.RUnlock()
+	set, exists := s.m[key]
+	if !exists {
+		return []string{}
+	}
+	return set.list()
+}
+
+// IsMember 检查元素是否在集合中
+func (s *SetStore) IsMember(key, member string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	set, exists := s.m[key]
+	return exists && set.contains(member)
+}
+
+// MembersExist 批量检查元素是否在集合中
+func (s *SetStore) MembersExist(key string, members []string) []bool {
+	s.RLock()
+	defer s.RUnlock()
+	result := make([]bool, len(members))
+	if set, exists := s.m[key]; exists {
+		for i, member := range members {
+			result[i] = set.contains(member)
+		}
+	}
+	return result
+}
+
+// Card 返回元素数量
+func (s *SetStore) Card(key string) int {
+	s.RLock()
+	defer s.RUnlock()
+	if set, exists := s.m[key]; exists {
+		return set.size()
+	}
+	return 0
+}
+
+// Pop 随机移除并返回至多 count 个元素；集合为空时删除键
+func (s *SetStore) Pop(key string, count int) []string {
+	s.Lock()
+	defer s.Unlock()
+	set, exists := s.m[key]
+	if !exists || count <= 0 {
+		return []string{}
+	}
+	if count >= set.size() {
+		delete(s.m, key)
+		return set.list()
+	}
+	popped := set.random(count, false)
+	for _, member := range popped {
+		set.remove(member)
+	}
+	return popped
+}
+
+// RandomMembers 随机返回元素
+// allowRepeats 为 false 时返回至多 count 个不重复元素，否则返回恰好 count 个（至多 MaxRandomRepeats 个）可能重复的元素
+// 索引按需建立，因此持有写锁
+func (s *SetStore) RandomMembers(key string, count int, allowRepeats bool) []string {
+	s.Lock()
+	defer s.Unlock()
+	set, exists := s.m[key]
+	if !exists || count <= 0 {
+		return []string{}
+	}
+	return set.random(count, allowRepeats)
+}
+
+// Move 将元素从 src 移动到 dst，元素不在 src 中时返回 false
+func (s *SetStore) Move(src, dst, member string) bool {
+	s.Lock()
+	defer s.Unlock()
+	srcSet, exists := s.m[src]
+	if !exists || !srcSet.contains(member) {
+		return false
+	}
+	if src == dst {
+		return true
+	}
+	srcSet.remove(member)
+	if srcSet.size() == 0 {
+		delete(s.m, src)
+	}
+	dstSet, exists := s.m[dst]
+	if !exists {
+		dstSet = &setObject{}
+		s.m[dst] = dstSet
+	}
+	dstSet.add(member)
+	return true
+}
+
+// Scan 按游标遍历元素，返回匹配的元素和下一个游标
+// 索引按需建立，因此持有写锁
+func (s *SetStore) Scan(key string, cursor uint64, match string, count int) ([]string, uint64) {
+	s.Lock()
+	defer s.Unlock()
+	set, exists := s.m[key]
+	if !exists {
+		return []string{}, 0
+	}
+	if set.size() <= scanSmallThreshold {
+		return scanAll(set.list(), match), 0
+	}
+	return set.index().scan(cursor, match, count, nil)
+}
+
+// sortedByCard 返回按元素数量升序排列的集合，任一集合不存在时返回 nil
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *SetStore) sortedByCard(keys []string) []*setObject {
+	sets := make([]*setObject, len(keys))
+	for i, key := range keys {
+		set, exists := s.m[key]
+		if !exists {
+			return nil
+		}
+		sets[i] = set
+	}
+	sort.SliceStable(sets, func(i, j int) bool { return sets[i].size() < sets[j].size() })
+	return sets
+}
+
+// inter 计算交集，从最小的集合开始遍历；limit > 0 时找到 limit 个元素后停止
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *SetStore) inter(keys []string, limit int) []string {
+	sets := s.sortedByCard(keys)
+	if len(sets) == 0 {
+		return []string{}
+	}
+	result := []string{}
+	for _, member := range sets[0].list() {
+		inAll := true
+		for _, other := range sets[1:] {
+			if other != sets[0] && !other.contains(member) {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			result = append(result, member)
+			if limit > 0 && len(result) >= limit {
+				break
+			}
+		}
+	}
+	return result
+}
+
+// union 计算并集
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *SetStore) union(keys []string) []string {
+	seen := make(map[string]struct{})
+	result := []string{}
+	for _, key := range keys {
+		set, exists := s.m[key]
+		if !exists {
+			continue
+		}
+		for _, member := range set.list() {
+			if _, ok := seen[member]; !ok {
+				seen[member] = struct{}{}
+				result = append(result, member)
+			}
+		}
+	}
+	return result
+}
+
+// diff 计算第一个集合与其余集合的差集
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *SetStore) diff(keys []string) []string {
+	first, exists := s.m[keys[0]]
+	if !exists {
+		return []string{}
+	}
+	result := []string{}
+	for _, member := range first.list() {
+		found := false
+		for _, key := range keys[1:] {
+			if other, ok := s.m[key]; ok && other.contains(member) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, member)
+		}
+	}
+	return result
+}
+
+// Inter 返回所有集合的交集
+func (s *SetStore) Inter(keys []string) []string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.inter(keys, 0)
+}
+
+// InterCard 返回交集的元素数量，limit > 0 时数到 limit 即停止
+func (s *SetStore) InterCard(keys []string, limit int) int {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.inter(keys, limit))
+}
+
+// Union 返回所有集合的并集
+func (s *SetStore) Union(keys []string) []string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.union(keys)
+}
+
+// Diff 返回第一个集合与其余集合的差集
+func (s *SetStore) Diff(keys []string) []string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.diff(keys)
+}
+
+// StoreMembers 用给定元素替换集合的全部内容，元素为空时删除键；返回集合的元素数量
+func (s *SetStore) StoreMembers(key string, members []string) int {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.m, key)
+	if len(members) == 0 {
+		return 0
+	}
+	set := &setObject{}
+	for _, member := range members {
+		set.add(member)
+	}
+	s.m[key] = set
+	return set.size()
+}
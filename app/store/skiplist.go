@@ -0,0 +1,321 @@
+package store
+
+import (
+	"math/rand"
+)
+
+const (
+	skiplistMaxLevel = 32   // 最大层数（与 Redis 相同）
+	skiplistP        = 0.25 // 每升一层的概率
+)
+
+// rangeSpec 描述跳表上的一个连续区间（按分数或按字典序）
+type rangeSpec interface {
+	isEmpty() bool
+	aboveMin(n *skiplistNode) bool
+	belowMax(n *skiplistNode) bool
+}
+
+// ScoreRange 表示分数区间，MinEx/MaxEx 为 true 时对应端点不包含在区间内
+type ScoreRange struct {
+	Min, Max     float64
+	MinEx, MaxEx bool
+}
+
+func (r ScoreRange) isEmpty() bool {
+	return r.Min > r.Max || (r.Min == r.Max && (r.MinEx || r.MaxEx))
+}
+
+func (r ScoreRange) aboveMin(n *skiplistNode) bool {
+	if r.MinEx {
+		return n.score > r.Min
+	}
+	return n.score >= r.Min
+}
+
+func (r ScoreRange) belowMax(n *skiplistNode) bool {
+	if r.MaxEx {
+		return n.score < r.Max
+	}
+	return n.score <= r.Max
+}
+
+// LexBound 表示字典序区间的一个端点，Inf 为 -1 表示 "-"（负无穷），为 1 表示 "+"（正无穷）
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Inf       int
+}
+
+// LexRange 表示字典序区间，仅在所有成员分数相同时有意义
+type LexRange struct {
+	Min, Max LexBound
+}
+
+func (r LexRange) isEmpty() bool {
+	if r.Min.Inf == 1 || r.Max.Inf == -1 {
+		return true
+	}
+	if r.Min.Inf == -1 || r.Max.Inf == 1 {
+		return false
+	}
+	return r.Min.Value > r.Max.Value ||
+		(r.Min.Value == r.Max.Value && (r.Min.Exclusive || r.Max.Exclusive))
+}
+
+func (r LexRange) aboveMin(n *skiplistNode) bool {
+	switch {
+	case r.Min.Inf != 0:
+		return r.Min.Inf < 0
+	case r.Min.Exclusive:
+		return n.member > r.Min.Value
+	default:
+		return n.member >= r.Min.Value
+	}
+}
+
+func (r LexRange) belowMax(n *skiplistNode) bool {
+	switch {
+	case r.Max.Inf != 0:
+		return r.Max.Inf > 0
+	case r.Max.Exclusive:
+		return n.member < r.Max.Value
+	default:
+		return n.member <= r.Max.Value
+	}
+}
+
+// skiplistLevel 节点在某一层的前进指针以及跨越的节点数
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	level    []skiplistLevel
+}
+
+// skiplist 按 (score, member) 升序排列的跳表，span 用于 O(log n) 计算排名
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// nodeBefore 判断节点是否排在 (score, member) 之前：分数相同时按成员的字典序排列
+func nodeBefore(n *skiplistNode, score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// insert 插入新节点，调用者需保证成员不存在
+func (zsl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		if i == zsl.level-1 {
+			rank[i] = 0
+		} else {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && nodeBefore(x.level[i].forward, score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = zsl.header
+			update[i].level[i].span = zsl.length
+		}
+		zsl.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, level: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	// 未触及的高层跨度加一
+	for i := level; i < zsl.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+	zsl.length++
+	return x
+}
+
+// deleteNode 从跳表中摘除节点，update 为每一层中位于 x 之前的节点
+func (zsl *skiplist) deleteNode(x *skiplistNode, update []*skiplistNode) {
+	for i := 0; i < zsl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+	for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+	zsl.length--
+}
+
+// delete 删除 (score, member) 对应的节点
+func (zsl *skiplist) delete(score float64, member string) bool {
+	update := make([]*skiplistNode, skiplistMaxLevel)
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && nodeBefore(x.level[i].forward, score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x != nil && x.score == score && x.member == member {
+		zsl.deleteNode(x, update)
+		return true
+	}
+	return false
+}
+
+// updateScore 修改成员的分数；位置不变时原地更新，否则删除后重新插入
+func (zsl *skiplist) updateScore(curScore float64, member string, newScore float64) *skiplistNode {
+	update := make([]*skiplistNode, skiplistMaxLevel)
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && nodeBefore(x.level[i].forward, curScore, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+
+	if (x.backward == nil || nodeBefore(x.backward, newScore, member)) &&
+		(x.level[0].forward == nil || !nodeBefore(x.level[0].forward, newScore, member)) {
+		x.score = newScore
+		return x
+	}
+	zsl.deleteNode(x, update)
+	return zsl.insert(newScore, member)
+}
+
+// rank 返回 (score, member) 的排名（从 1 开始），不存在时返回 0
+func (zsl *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(nodeBefore(x.level[i].forward, score, member) ||
+				(x.level[i].forward.score == score && x.level[i].forward.member == member)) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+		if x != zsl.header && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank 返回指定排名（从 1 开始）的节点
+func (zsl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+// isInRange 判断跳表与区间是否有交集
+func (zsl *skiplist) isInRange(r rangeSpec) bool {
+	if r.isEmpty() {
+		return false
+	}
+	if zsl.tail == nil || !r.aboveMin(zsl.tail) {
+		return false
+	}
+	first := zsl.header.level[0].forward
+	return first != nil && r.belowMax(first)
+}
+
+// firstInRange 返回区间内的第一个节点
+func (zsl *skiplist) firstInRange(r rangeSpec) *skiplistNode {
+	if !zsl.isInRange(r) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.aboveMin(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.belowMax(x) {
+		return nil
+	}
+	return x
+}
+
+// lastInRange 返回区间内的最后一个节点
+func (zsl *skiplist) lastInRange(r rangeSpec) *skiplistNode {
+	if !zsl.isInRange(r) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.belowMax(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+	if x == zsl.header || !r.aboveMin(x) {
+		return nil
+	}
+	return x
+}
@@ -0,0 +1,7 @@
+package store
+
+// KeyOps 定义所有类型的存储都支持的键操作
+type KeyOps interface {
+	Exists(key string) bool
+	Delete(key string) bool
+}
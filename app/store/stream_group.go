@@ -0,0 +1,630 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+var (
+	ErrBusyGroup         = &resp.Error{Code: "BUSYGROUP", Message: "Consumer Group name already exists"}
+	ErrStreamKeyRequired = errors.New("The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+)
+
+// pendingEntry 表示已投递给消费者但尚未确认的条目
+type pendingEntry struct {
+	consumer      string
+	deliveryTime  time.Time
+	deliveryCount int
+}
+
+// streamConsumer 表示消费者组中的一个消费者
+type streamConsumer struct {
+	name       string
+	seenTime   time.Time    // 最后一次尝试读取的时间
+	activeTime time.Time    // 最后一次成功读到条目的时间，从未读到时为零值
+	pending    *pendingList // 该消费者的待确认条目，与组的 PEL 共享 pendingEntry
+}
+
+// info 返回待确认条目的信息
+func (pe *pendingEntry) info(id StreamID, now time.Time) PendingInfo {
+	return PendingInfo{
+		ID:            id,
+		Consumer:      pe.consumer,
+		DeliveryTime:  pe.deliveryTime,
+		Idle:          now.Sub(pe.deliveryTime),
+		DeliveryCount: pe.deliveryCount,
+	}
+}
+
+// consumerGroup 表示流的一个消费者组
+type consumerGroup struct {
+	lastID      StreamID     // 最后投递的 ID
+	entriesRead int64        // 组已读取的条目数，-1 表示未知
+	pending     *pendingList // 组的待确认条目列表（PEL）
+	consumers   map[string]*streamConsumer
+}
+
+func newConsumerGroup(lastID StreamID, entriesRead int64) *consumerGroup {
+	return &consumerGroup{
+		lastID:      lastID,
+		entriesRead: entriesRead,
+		pending:     newPendingList(),
+		consumers:   make(map[string]*streamConsumer),
+	}
+}
+
+// consumer 返回指定的消费者，不存在时创建；同时更新最后一次尝试读取的时间
+func (cg *consumerGroup) consumer(name string, now time.Time) *streamConsumer {
+	c, exists := cg.consumers[name]
+	if !exists {
+		c = &streamConsumer{name: name, pending: newPendingList()}
+		cg.consumers[name] = c
+	}
+	c.seenTime = now
+	return c
+}
+
+// ack 从组和消费者的 PEL 中移除条目，返回条目是否在 PEL 中
+func (cg *consumerGroup) ack(id StreamID) bool {
+	pe, exists := cg.pending.get(id)
+	if !exists {
+		return false
+	}
+	cg.pending.remove(id)
+	if c, ok := cg.consumers[pe.consumer]; ok {
+		c.pending.remove(id)
+	}
+	return true
+}
+
+// pendingList 是按 ID 升序排列的待确认条目列表，按范围读取时不需要排序
+// 新投递的条目 ID 总是最大的，追加到末尾
+type pendingList struct {
+	ids     []StreamID
+	entries map[StreamID]*pendingEntry
+}
+
+func newPendingList() *pendingList {
+	return &pendingList{entries: make(map[StreamID]*pendingEntry)}
+}
+
+func (pl *pendingList) len() int {
+	return len(pl.ids)
+}
+
+func (pl *pendingList) get(id StreamID) (*pendingEntry, bool) {
+	pe, exists := pl.entries[id]
+	return pe, exists
+}
+
+// search 返回第一个不小于 id 的条目的下标
+func (pl *pendingList) search(id StreamID) int {
+	return sort.Search(len(pl.ids), func(i int) bool { return pl.ids[i].Compare(id) >= 0 })
+}
+
+// add 加入或替换条目
+func (pl *pendingList) add(id StreamID, pe *pendingEntry) {
+	if _, exists := pl.entries[id]; !exists {
+		if n := len(pl.ids); n == 0 || pl.ids[n-1].Compare(id) < 0 {
+			pl.ids = append(pl.ids, id)
+		} else {
+			i := pl.search(id)
+			pl.ids = append(pl.ids, StreamID{})
+			copy(pl.ids[i+1:], pl.ids[i:])
+			pl.ids[i] = id
+		}
+	}
+	pl.entries[id] = pe
+}
+
+// remove 删除条目，返回条目是否存在
+func (pl *pendingList) remove(id StreamID) bool {
+	if _, exists := pl.entries[id]; !exists {
+		return false
+	}
+	delete(pl.entries, id)
+	i := pl.search(id)
+	pl.ids = append(pl.ids[:i], pl.ids[i+1:]...)
+	return true
+}
+
+// removeAll 删除 other 中的所有条目，只遍历一次列表
+func (pl *pendingList) removeAll(other *pendingList) {
+	kept := pl.ids[:0]
+	for _, id := range pl.ids {
+		if _, exists := other.entries[id]; exists {
+			delete(pl.entries, id)
+		} else {
+			kept = append(kept, id)
+		}
+	}
+	pl.ids = kept
+}
+
+// deliverNew 把组最后投递 ID 之后的新条目投递给消费者，count 为 0 表示不限制数量
+// noAck 为 false 时把投递的条目加入组和消费者的 PEL
+func (st *stream) deliverNew(cg *consumerGroup, c *streamConsumer, count int, noAck bool, now time.Time) []StreamEntry {
+	result := st.readAfter(cg.lastID, count)
+	for _, entry := range result {
+		// 之后没有墓碑时计数仍然准确，可以直接递增，否则重新估算
+		if cg.entriesRead != -1 && !st.hasTombstonesAfter(entry.ID) {
+			cg.entriesRead++
+		} else if st.entriesAdded > 0 {
+			cg.entriesRead = st.estimateEntriesRead(entry.ID)
+		}
+		cg.lastID = entry.ID
+		if !noAck {
+			// SETID 回退后再次投递的条目会转移给当前消费者
+			cg.ack(entry.ID)
+			pe := &pendingEntry{consumer: c.name, deliveryTime: now, deliveryCount: 1}
+			cg.pending.add(entry.ID, pe)
+			c.pending.add(entry.ID, pe)
+		}
+	}
+	if len(result) > 0 {
+		c.activeTime = now
+	}
+	return result
+}
+
+// history 返回消费者 PEL 中 ID 大于 startID 的条目，已被删除的条目只有 ID（Fields 为 nil）
+func (st *stream) history(c *streamConsumer, startID StreamID, count int) []StreamEntry {
+	result := []StreamEntry{}
+	i := c.pending.search(startID)
+	if i < c.pending.len() && c.pending.ids[i] == startID {
+		i++
+	}
+	for _, id := range c.pending.ids[i:] {
+		if count > 0 && len(result) >= count {
+			break
+		}
+		if entry := st.find(id); entry != nil {
+			result = append(result, *entry)
+		} else {
+			result = append(result, StreamEntry{ID: id})
+		}
+	}
+	return result
+}
+
+// resolveGroupID 解析 XGROUP CREATE/SETID 的 ID 参数，"$" 表示流最后添加的 ID
+func (st *stream) resolveGroupID(id string) (StreamID, error) {
+	if id == "$" {
+		return st.lastID, nil
+	}
+	return ParseStreamID(id)
+}
+
+// group 返回指定的流和消费者组，流不存在时返回 ErrStreamKeyRequired
+func (s *StreamStore) group(key, group string) (*stream, *consumerGroup, error) {
+	st, exists := s.streams[key]
+	if !exists {
+		return nil, nil, ErrStreamKeyRequired
+	}
+	cg, exists := st.groups[group]
+	if !exists {
+		return nil, nil, resp.NewError("NOGROUP", "No such consumer group '%s' for key name '%s'", group, key)
+	}
+	return st, cg, nil
+}
+
+// CreateGroup 创建消费者组，mkStream 为 true 时在流不存在时创建空流
+func (s *StreamStore) CreateGroup(key, group, id string, mkStream bool, entriesRead int64) error {
+	s.Lock()
+	defer s.Unlock()
+	st, exists := s.streams[key]
+	if !exists {
+		if !mkStream {
+			return ErrStreamKeyRequired
+		}
+		st = &stream{}
+		s.streams[key] = st
+	}
+	lastID, err := st.resolveGroupID(id)
+	if err != nil {
+		return err
+	}
+	if _, exists := st.groups[group]; exists {
+		return ErrBusyGroup
+	}
+	if st.groups == nil {
+		st.groups = make(map[string]*consumerGroup)
+	}
+	st.groups[group] = newConsumerGroup(lastID, entriesRead)
+	return nil
+}
+
+// SetGroupID 修改消费者组最后投递的 ID
+func (s *StreamStore) SetGroupID(key, group, id string, entriesRead int64) error {
+	s.Lock()
+	defer s.Unlock()
+	st, cg, err := s.group(key, group)
+	if err != nil {
+		return err
+	}
+	lastID, err := st.resolveGroupID(id)
+	if err != nil {
+		return err
+	}
+	cg.lastID = lastID
+	cg.entriesRead = entriesRead
+	return nil
+}
+
+// DestroyGroup 删除消费者组，返回组是否存在
+func (s *StreamStore) DestroyGroup(key, group string) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	st, exists := s.streams[key]
+	if !exists {
+		return false, ErrStreamKeyRequired
+	}
+	if _, exists := st.groups[group]; !exists {
+		return false, nil
+	}
+	delete(st.groups, group)
+	// 唤醒阻塞在该组上的 XREADGROUP，让它们收到 NOGROUP 错误
+	s.blocked.serveEach(key)
+	return true, nil
+}
+
+// CreateConsumer 在组中创建消费者，返回是否新建
+func (s *StreamStore) CreateConsumer(key, group, consumer string) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	_, cg, err := s.group(key, group)
+	if err != nil {
+		return false, err
+	}
+	if _, exists := cg.consumers[consumer]; exists {
+		return false, nil
+	}
+	cg.consumer(consumer, time.Now())
+	return true, nil
+}
+
+// DeleteConsumer 删除消费者及其待确认条目，返回删除前该消费者的待确认条目数
+func (s *StreamStore) DeleteConsumer(key, group, consumer string) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	_, cg, err := s.group(key, group)
+	if err != nil {
+		return 0, err
+	}
+	c, exists := cg.consumers[consumer]
+	if !exists {
+		return 0, nil
+	}
+	pending := c.pending.len()
+	cg.pending.removeAll(c.pending)
+	delete(cg.consumers, consumer)
+	return pending, nil
+}
+
+// GroupDelivery 记录 XREADGROUP 在一个流上投递新条目后组的状态，包含复制投递所需的全部信息
+type GroupDelivery struct {
+	Time        time.Time // 投递时间，即新的待确认条目的投递时间
+	LastID      StreamID  // 组最后投递的 ID
+	EntriesRead int64     // 组已读取的条目数
+}
+
+// delivery 返回组在 now 投递条目后的状态
+func (cg *consumerGroup) delivery(now time.Time) GroupDelivery {
+	return GroupDelivery{Time: now, LastID: cg.lastID, EntriesRead: cg.entriesRead}
+}
+
+// readGroupLocked 检查所有键的消费者组是否存在以及 ID 是否有效，然后逐个读取
+// ID 为 ">" 时投递新条目，只在有条目时出现在结果和投递记录中；否则读取消费者的历史，总是出现在结果中
+// 必须在调用者持有写锁的情况下调用
+func (s *StreamStore) readGroupLocked(keys, ids []string, group, consumer string, count int, noAck bool) (map[string][]StreamEntry, map[string]GroupDelivery, error) {
+	starts := make([]StreamID, len(keys))
+	for i, key := range keys {
+		st, exists := s.streams[key]
+		if !exists || st.groups[group] == nil {
+			return nil, nil, resp.NewError("NOGROUP", "No such key '%s' or consumer group '%s' in XREADGROUP with GROUP option", key, group)
+		}
+		if ids[i] != ">" {
+			var err error
+			if starts[i], err = ParseStreamID(ids[i]); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	now := time.Now()
+	result := make(map[string][]StreamEntry)
+	deliveries := make(map[string]GroupDelivery)
+	for i, key := range keys {
+		st := s.streams[key]
+		cg := st.groups[group]
+		c := cg.consumer(consumer, now)
+		if ids[i] == ">" {
+			if entries := st.deliverNew(cg, c, count, noAck, now); len(entries) > 0 {
+				result[key] = entries
+				deliveries[key] = cg.delivery(now)
+			}
+			continue
+		}
+		result[key] = st.history(c, starts[i], count)
+	}
+	return result, deliveries, nil
+}
+
+// ReadGroup 实现 XREADGROUP 的非阻塞读取，ids 中的每一项是 ">" 或者 ID
+func (s *StreamStore) ReadGroup(keys, ids []string, group, consumer string, count int, noAck bool) (map[string][]StreamEntry, map[string]GroupDelivery, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.readGroupLocked(keys, ids, group, consumer, count, noAck)
+}
+
+// ReadGroupBlocking 读取所有键上的新条目，没有新条目时阻塞直到 XADD 添加条目、超时或 ctx 被取消
+// 超时返回空结果，被取消时返回取消原因，阻塞期间消费者组被删除时返回 NOGROUP 错误
+func (s *StreamStore) ReadGroupBlocking(ctx context.Context, keys []string, group, consumer string, count int, noAck bool, timeout time.Duration) (map[string][]StreamEntry, map[string]GroupDelivery, error) {
+	ids := make([]string, len(keys))
+	for i := range ids {
+		ids[i] = ">"
+	}
+	s.Lock()
+	result, deliveries, err := s.readGroupLocked(keys, ids, group, consumer, count, noAck)
+	if err != nil || len(result) > 0 {
+		s.Unlock()
+		return result, deliveries, err
+	}
+	var served []StreamEntry
+	var delivery GroupDelivery
+	var servedErr error
+	w := s.blocked.block(keys, func(key string) bool {
+		st, exists := s.streams[key]
+		if !exists || st.groups[group] == nil {
+			servedErr = resp.NewError("NOGROUP", "the consumer group this client was blocked on no longer exists")
+			return true
+		}
+		cg, now := st.groups[group], time.Now()
+		served = st.deliverNew(cg, cg.consumer(consumer, now), count, noAck, now)
+		delivery = cg.delivery(now)
+		return len(served) > 0
+	})
+	s.Unlock()
+
+	key, err := s.blocked.wait(ctx, s, w, timeout)
+	if err != nil || key == "" {
+		return map[string][]StreamEntry{}, nil, err
+	}
+	if servedErr != nil {
+		return nil, nil, servedErr
+	}
+	return map[string][]StreamEntry{key: served}, map[string]GroupDelivery{key: delivery}, nil
+}
+
+// Ack 确认消费者组中的条目，返回实际确认的数量；流或组不存在时返回 0
+func (s *StreamStore) Ack(key, group string, ids []string) (int, error) {
+	parsed, err := parseStreamIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+	s.Lock()
+	defer s.Unlock()
+	st, exists := s.streams[key]
+	if !exists || st.groups[group] == nil {
+		return 0, nil
+	}
+	acked := 0
+	for _, id := range parsed {
+		if st.groups[group].ack(id) {
+			acked++
+		}
+	}
+	return acked, nil
+}
+
+// ConsumerPending 表示一个消费者的待确认条目数
+type ConsumerPending struct {
+	Name  string
+	Count int
+}
+
+// PendingSummary 是 XPENDING 概要形式的结果
+type PendingSummary struct {
+	Count        int
+	MinID, MaxID StreamID
+	Consumers    []ConsumerPending // 按名称排序，只包含有待确认条目的消费者
+}
+
+// PendingRangeOptions 是 XPENDING 扩展形式的参数
+type PendingRangeOptions struct {
+	Start, End StreamID
+	Count      int
+	Consumer   string        // 非空时只返回该消费者的条目
+	MinIdle    time.Duration // 只返回空闲时间不小于 MinIdle 的条目
+}
+
+// PendingInfo 表示一个待确认条目
+type PendingInfo struct {
+	ID            StreamID
+	Consumer      string
+	DeliveryTime  time.Time
+	Idle          time.Duration
+	DeliveryCount int
+}
+
+// ClaimOptions 是 XCLAIM 和 XAUTOCLAIM 的选项
+type ClaimOptions struct {
+	MinIdle      time.Duration
+	DeliveryTime time.Time // 非零时作为认领后的投递时间（IDLE/TIME），否则为当前时间
+	RetryCount   int       // 非负时作为认领后的投递次数（RETRYCOUNT），否则投递次数加一
+	Force        bool      // 条目不在 PEL 中时也加入 PEL
+	JustID       bool      // 只返回 ID，且不增加投递次数
+	LastID       StreamID  // 大于组最后投递的 ID 时把它推进到该 ID
+}
+
+// ClaimedEntry 表示被认领的条目及认领后的投递信息
+type ClaimedEntry struct {
+	StreamEntry
+	DeliveryTime  time.Time
+	DeliveryCount int
+}
+
+// ClaimResult 是 XCLAIM 和 XAUTOCLAIM 的结果，包含复制认领所需的全部信息
+type ClaimResult struct {
+	Claimed     []ClaimedEntry
+	Deleted     []StreamID // 已从流中删除、因此从 PEL 中移除的 ID
+	NextID      StreamID   // XAUTOCLAIM 下一次扫描的起点，扫描完毕时为 0-0
+	LastID      StreamID   // LASTID 推进了组最后投递的 ID 时为新的 ID，否则为 0-0
+	EntriesRead int64      // 组已读取的条目数
+}
+
+// pendingGroup 返回指定的流和消费者组，不存在时返回 NOGROUP 错误
+func (s *StreamStore) pendingGroup(key, group string) (*stream, *consumerGroup, error) {
+	st, exists := s.streams[key]
+	if !exists || st.groups[group] == nil {
+		return nil, nil, resp.NewError("NOGROUP", "No such key '%s' or consumer group '%s'", key, group)
+	}
+	return st, st.groups[group], nil
+}
+
+// PendingSummary 返回组的待确认条目概要
+func (s *StreamStore) PendingSummary(key, group string) (PendingSummary, error) {
+	s.RLock()
+	defer s.RUnlock()
+	_, cg, err := s.pendingGroup(key, group)
+	if err != nil {
+		return PendingSummary{}, err
+	}
+	summary := PendingSummary{Count: cg.pending.len()}
+	if summary.Count == 0 {
+		return summary, nil
+	}
+	ids := cg.pending.ids
+	summary.MinID, summary.MaxID = ids[0], ids[len(ids)-1]
+	for name, c := range cg.consumers {
+		if c.pending.len() > 0 {
+			summary.Consumers = append(summary.Consumers, ConsumerPending{Name: name, Count: c.pending.len()})
+		}
+	}
+	sort.Slice(summary.Consumers, func(i, j int) bool { return summary.Consumers[i].Name < summary.Consumers[j].Name })
+	return summary, nil
+}
+
+// PendingRange 按 ID 顺序返回区间内的待确认条目
+func (s *StreamStore) PendingRange(key, group string, opts PendingRangeOptions) ([]PendingInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	_, cg, err := s.pendingGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+	pending := cg.pending
+	if opts.Consumer != "" {
+		c, exists := cg.consumers[opts.Consumer]
+		if !exists {
+			return []PendingInfo{}, nil
+		}
+		pending = c.pending
+	}
+	now := time.Now()
+	result := []PendingInfo{}
+	for _, id := range pending.ids[pending.search(opts.Start):] {
+		if len(result) >= opts.Count || id.Compare(opts.End) > 0 {
+			break
+		}
+		pe := pending.entries[id]
+		if now.Sub(pe.deliveryTime) < opts.MinIdle {
+			continue
+		}
+		result = append(result, pe.info(id, now))
+	}
+	return result, nil
+}
+
+// claim 尝试把条目认领给消费者，结果记录在 result 中
+// PEL 中的条目已从流中删除时从 PEL 中移除；空闲时间不足 MinIdle 时不认领
+func (st *stream) claim(cg *consumerGroup, c *streamConsumer, id StreamID, opts ClaimOptions, now time.Time, result *ClaimResult) {
+	pe, pending := cg.pending.get(id)
+	entry := st.find(id)
+	switch {
+	case pending && entry == nil:
+		cg.ack(id)
+		result.Deleted = append(result.Deleted, id)
+		return
+	case !pending && (!opts.Force || entry == nil):
+		return
+	case pending && now.Sub(pe.deliveryTime) < opts.MinIdle:
+		return
+	}
+	if !pending {
+		// FORCE 创建的条目与 Redis 一样从投递一次开始计数
+		pe = &pendingEntry{deliveryCount: 1}
+		cg.pending.add(id, pe)
+	} else if old, ok := cg.consumers[pe.consumer]; ok {
+		old.pending.remove(id)
+	}
+	pe.consumer = c.name
+	c.pending.add(id, pe)
+	c.activeTime = now
+
+	pe.deliveryTime = now
+	if !opts.DeliveryTime.IsZero() {
+		pe.deliveryTime = opts.DeliveryTime
+	}
+	if opts.RetryCount >= 0 {
+		pe.deliveryCount = opts.RetryCount
+	} else if !opts.JustID {
+		pe.deliveryCount++
+	}
+	result.Claimed = append(result.Claimed, ClaimedEntry{StreamEntry: *entry, DeliveryTime: pe.deliveryTime, DeliveryCount: pe.deliveryCount})
+}
+
+// Claim 把指定的待确认条目转移给消费者（XCLAIM）
+func (s *StreamStore) Claim(key, group, consumer string, ids []StreamID, opts ClaimOptions) (ClaimResult, error) {
+	s.Lock()
+	defer s.Unlock()
+	st, cg, err := s.pendingGroup(key, group)
+	if err != nil {
+		return ClaimResult{}, err
+	}
+	now := time.Now()
+	c := cg.consumer(consumer, now)
+	result := ClaimResult{}
+	if opts.LastID.Compare(cg.lastID) > 0 {
+		cg.lastID = opts.LastID
+		result.LastID = opts.LastID
+	}
+	for _, id := range ids {
+		st.claim(cg, c, id, opts, now, &result)
+	}
+	result.EntriesRead = cg.entriesRead
+	return result, nil
+}
+
+// AutoClaimAttemptsFactor 是 XAUTOCLAIM 每次最多检查的条目数与 COUNT 之比
+const AutoClaimAttemptsFactor = 10
+
+// AutoClaim 从 start 开始按 ID 顺序扫描 PEL，认领至多 count 个空闲时间足够长的条目（XAUTOCLAIM）
+// 最多检查 count*AutoClaimAttemptsFactor 个条目，已删除的条目从 PEL 中移除并记录在 Deleted 中
+func (s *StreamStore) AutoClaim(key, group, consumer string, start StreamID, count int, opts ClaimOptions) (ClaimResult, error) {
+	s.Lock()
+	defer s.Unlock()
+	st, cg, err := s.pendingGroup(key, group)
+	if err != nil {
+		return ClaimResult{}, err
+	}
+	now := time.Now()
+	c := cg.consumer(consumer, now)
+	result := ClaimResult{EntriesRead: cg.entriesRead}
+	// 认领时可能从 PEL 中删除条目，先复制本次最多检查的 ID，以及下一次扫描的起点
+	attempts := count * AutoClaimAttemptsFactor
+	ids := cg.pending.ids[cg.pending.search(start):]
+	ids = append([]StreamID(nil), ids[:min(len(ids), attempts+1)]...)
+	i := 0
+	for ; i < len(ids) && attempts > 0 && len(result.Claimed) < count; i++ {
+		st.claim(cg, c, ids[i], opts, now, &result)
+		attempts--
+	}
+	if i < len(ids) {
+		result.NextID = ids[i]
+	}
+	return result, nil
+}
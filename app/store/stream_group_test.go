@@ -0,0 +1,48 @@
+package store
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestPendingListOrder(t *testing.T) {
+	pl := newPendingList()
+	want := map[StreamID]bool{}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		id := StreamID{Ms: uint64(rng.Intn(300)), Seq: uint64(rng.Intn(3))}
+		if rng.Intn(3) == 0 {
+			if pl.remove(id) != want[id] {
+				t.Fatalf("remove(%v) disagrees with the reference set", id)
+			}
+			delete(want, id)
+			continue
+		}
+		pl.add(id, &pendingEntry{})
+		want[id] = true
+	}
+
+	other := newPendingList()
+	for id := range want {
+		if rng.Intn(2) == 0 {
+			other.add(id, &pendingEntry{})
+			delete(want, id)
+		}
+	}
+	pl.removeAll(other)
+
+	ids := make([]StreamID, 0, len(want))
+	for id := range want {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, StreamID.Compare)
+	if !slices.Equal(pl.ids, ids) || len(pl.entries) != len(ids) {
+		t.Fatalf("ids = %v, want %v", pl.ids, ids)
+	}
+	for _, id := range ids {
+		if i := pl.search(id); pl.ids[i] != id {
+			t.Fatalf("search(%v) = %d", id, i)
+		}
+	}
+}
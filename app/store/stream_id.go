@@ -0,0 +1,116 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// StreamID 是流条目的 ID，由毫秒时间和序列号两个无符号整数组成
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+// maxStreamID 是最大的 ID，对应范围端点 "+"
+var maxStreamID = StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+// String 返回 ms-seq 形式
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// Compare 比较两个 ID，返回 -1、0 或 1
+func (id StreamID) Compare(other StreamID) int {
+	switch {
+	case id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq):
+		return -1
+	case id == other:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// IsZero 判断是否为 0-0
+func (id StreamID) IsZero() bool {
+	return id == StreamID{}
+}
+
+// next 返回紧随其后的 ID，已是最大的 ID 时第二个返回值为 false
+func (id StreamID) next() (StreamID, bool) {
+	switch {
+	case id.Seq < math.MaxUint64:
+		return StreamID{Ms: id.Ms, Seq: id.Seq + 1}, true
+	case id.Ms < math.MaxUint64:
+		return StreamID{Ms: id.Ms + 1}, true
+	default:
+		return id, false
+	}
+}
+
+// prev 返回紧邻其前的 ID，已是 0-0 时第二个返回值为 false
+func (id StreamID) prev() (StreamID, bool) {
+	switch {
+	case id.Seq > 0:
+		return StreamID{Ms: id.Ms, Seq: id.Seq - 1}, true
+	case id.Ms > 0:
+		return StreamID{Ms: id.Ms - 1, Seq: math.MaxUint64}, true
+	default:
+		return id, false
+	}
+}
+
+// parseStreamID 解析 ms-seq 形式的 ID，省略序列号时使用 missingSeq
+func parseStreamID(s string, missingSeq uint64) (StreamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return StreamID{}, ErrInvalidStreamID
+	}
+	seq := missingSeq
+	if hasSeq {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return StreamID{}, ErrInvalidStreamID
+		}
+	}
+	return StreamID{Ms: ms, Seq: seq}, nil
+}
+
+// ParseStreamID 解析命令参数中的 ID，省略序列号时视为 0
+func ParseStreamID(s string) (StreamID, error) {
+	return parseStreamID(s, 0)
+}
+
+// ParseRangeID 解析范围端点
+// 支持 "-"、"+"、省略序列号（起点补 0，终点补最大值），以及 "(" 前缀表示不包含端点
+func ParseRangeID(s string, isEnd bool) (StreamID, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	switch {
+	case s == "-" && !exclusive:
+		return StreamID{}, nil
+	case s == "+" && !exclusive:
+		return maxStreamID, nil
+	case isEnd:
+		id, err := parseStreamID(s, math.MaxUint64)
+		if err != nil || !exclusive {
+			return id, err
+		}
+		if id, ok := id.prev(); ok {
+			return id, nil
+		}
+		return StreamID{}, fmt.Errorf("invalid end ID for the interval")
+	}
+	id, err := parseStreamID(s, 0)
+	if err != nil || !exclusive {
+		return id, err
+	}
+	if id, ok := id.next(); ok {
+		return id, nil
+	}
+	return StreamID{}, fmt.Errorf("invalid start ID for the interval")
+}
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrNoSuchKey = errors.New("no such key")
+
+// firstID 返回第一个未删除条目的 ID，流为空时为 0-0
+func (st *stream) firstID() StreamID {
+	if first := st.first(); first != nil {
+		return first.ID
+	}
+	return StreamID{}
+}
+
+// hasTombstonesAfter 判断 ID 不小于 start 的范围内是否可能有被 XDEL 删除的条目
+func (st *stream) hasTombstonesAfter(start StreamID) bool {
+	if st.length == 0 || st.maxDeletedID.IsZero() {
+		return false
+	}
+	return start.Compare(st.maxDeletedID) <= 0
+}
+
+// estimateEntriesRead 估算读到 id 为止读取过的条目数（即 id 在所有添加过的条目中的逻辑位置），
+// 无法确定时返回 -1；与 Redis 的 streamEstimateDistanceFromFirstEverEntry 相同
+func (st *stream) estimateEntriesRead(id StreamID) int64 {
+	if st.entriesAdded == 0 {
+		return 0
+	}
+	if st.length == 0 && id.Compare(st.lastID) <= 0 {
+		return st.entriesAdded
+	}
+	switch cmp := id.Compare(st.lastID); {
+	case cmp == 0:
+		return st.entriesAdded
+	case cmp > 0:
+		return -1 // 未来的 ID 无法确定
+	}
+	// 第一个条目之前没有删除过条目时，中间没有空洞，可以由长度推算
+	first := st.firstID()
+	if st.maxDeletedID.Compare(first) < 0 {
+		switch cmp := id.Compare(first); {
+		case cmp < 0:
+			return st.entriesAdded - int64(st.length)
+		case cmp == 0:
+			return st.entriesAdded - int64(st.length) + 1
+		}
+	}
+	return -1
+}
+
+// lag 返回组尚未读取的条目数，无法确定时第二个返回值为 false
+func (st *stream) lag(cg *consumerGroup) (int64, bool) {
+	if st.entriesAdded == 0 {
+		return 0, true
+	}
+	if cg.entriesRead != -1 && !st.hasTombstonesAfter(cg.lastID) {
+		return st.entriesAdded - cg.entriesRead, true
+	}
+	if entriesRead := st.estimateEntriesRead(cg.lastID); entriesRead != -1 {
+		return st.entriesAdded - entriesRead, true
+	}
+	return 0, false
+}
+
+// ConsumerInfo 是 XINFO CONSUMERS 和 XINFO STREAM FULL 中一个消费者的信息
+type ConsumerInfo struct {
+	Name           string
+	Pending        int
+	SeenTime       time.Time
+	ActiveTime     time.Time // 从未成功读到条目时为零值
+	Idle           time.Duration
+	Inactive       time.Duration // ActiveTime 为零值时无意义
+	PendingEntries []PendingInfo // 只在 FULL 中填充
+}
+
+// GroupInfo 是 XINFO GROUPS 和 XINFO STREAM FULL 中一个消费者组的信息
+type GroupInfo struct {
+	Name            string
+	Consumers       int
+	Pending         int
+	LastDeliveredID StreamID
+	EntriesRead     int64 // -1 表示未知
+	Lag             int64
+	LagValid        bool
+	PendingEntries  []PendingInfo  // 只在 FULL 中填充
+	ConsumerDetails []ConsumerInfo // 只在 FULL 中填充
+}
+
+// StreamInfo 是 XINFO STREAM 的结果
+type StreamInfo struct {
+	Length               int
+	LastGeneratedID      StreamID
+	MaxDeletedEntryID    StreamID
+	EntriesAdded         int64
+	RecordedFirstEntryID StreamID
+	Groups               int
+	FirstEntry           *StreamEntry // 流为空时为 nil
+	LastEntry            *StreamEntry
+	Entries              []StreamEntry // 只在 FULL 中填充
+	GroupDetails         []GroupInfo   // 只在 FULL 中填充
+}
+
+// sortedGroupNames 返回按名称排序的组名
+func (st *stream) sortedGroupNames() []string {
+	names := make([]string, 0, len(st.groups))
+	for name := range st.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// groupInfo 生成组的信息，full 为 true 时填充至多 count 个待确认条目（count 为 0 表示全部）和消费者详情
+func (st *stream) groupInfo(name string, full bool, count int, now time.Time) GroupInfo {
+	cg := st.groups[name]
+	info := GroupInfo{
+		Name:            name,
+		Consumers:       len(cg.consumers),
+		Pending:         cg.pending.len(),
+		LastDeliveredID: cg.lastID,
+		EntriesRead:     cg.entriesRead,
+	}
+	info.Lag, info.LagValid = st.lag(cg)
+	if !full {
+		return info
+	}
+	info.PendingEntries = pendingInfos(cg.pending, count, now)
+	for _, c := range cg.sortedConsumers() {
+		consumer := c.info(now)
+		consumer.PendingEntries = pendingInfos(c.pending, count, now)
+		info.ConsumerDetails = append(info.ConsumerDetails, consumer)
+	}
+	return info
+}
+
+// pendingInfos 按 ID 顺序返回至多 count 个待确认条目的信息，count 为 0 表示全部
+func pendingInfos(pending *pendingList, count int, now time.Time) []PendingInfo {
+	result := []PendingInfo{}
+	for _, id := range pending.ids {
+		if count > 0 && len(result) >= count {
+			break
+		}
+		result = append(result, pending.entries[id].info(id, now))
+	}
+	return result
+}
+
+// sortedConsumers 返回按名称排序的消费者
+func (cg *consumerGroup) sortedConsumers() []*streamConsumer {
+	consumers := make([]*streamConsumer, 0, len(cg.consumers))
+	for _, c := range cg.consumers {
+		consumers = append(consumers, c)
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].name < consumers[j].name })
+	return consumers
+}
+
+// info 返回消费者的信息
+func (c *streamConsumer) info(now time.Time) ConsumerInfo {
+	info := ConsumerInfo{
+		Name:       c.name,
+		Pending:    c.pending.len(),
+		SeenTime:   c.seenTime,
+		ActiveTime: c.activeTime,
+		Idle:       now.Sub(c.seenTime),
+		Inactive:   now.Sub(c.activeTime),
+	}
+	return info
+}
+
+// StreamInfo 返回流的信息（XINFO STREAM），流不存在时返回 ErrNoSuchKey
+// full 为 true 时填充至多 count 个条目以及所有组的详情，count 为 0 表示全部
+func (s *StreamStore) StreamInfo(key string, full bool, count int) (StreamInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	st, exists := s.streams[key]
+	if !exists {
+		return StreamInfo{}, ErrNoSuchKey
+	}
+	info := StreamInfo{
+		Length:               st.length,
+		LastGeneratedID:      st.lastID,
+		MaxDeletedEntryID:    st.maxDeletedID,
+		EntriesAdded:         st.entriesAdded,
+		RecordedFirstEntryID: st.firstID(),
+		Groups:               len(st.groups),
+	}
+	if !full {
+		info.FirstEntry, info.LastEntry = st.first(), st.last()
+		return info, nil
+	}
+	info.Entries = st.rangeEntries(StreamID{}, maxStreamID, count, false)
+	now := time.Now()
+	for _, name := range st.sortedGroupNames() {
+		info.GroupDetails = append(info.GroupDetails, st.groupInfo(name, true, count, now))
+	}
+	return info, nil
+}
+
+// GroupsInfo 返回流的所有消费者组的信息（XINFO GROUPS），流不存在时返回 ErrNoSuchKey
+func (s *StreamStore) GroupsInfo(key string) ([]GroupInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	st, exists := s.streams[key]
+	if !exists {
+		return nil, ErrNoSuchKey
+	}
+	now := time.Now()
+	result := []GroupInfo{}
+	for _, name := range st.sortedGroupNames() {
+		result = append(result, st.groupInfo(name, false, 0, now))
+	}
+	return result, nil
+}
+
+// ConsumersInfo 返回消费者组中所有消费者的信息（XINFO CONSUMERS）
+func (s *StreamStore) ConsumersInfo(key, group string) ([]ConsumerInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if _, exists := s.streams[key]; !exists {
+		return nil, ErrNoSuchKey
+	}
+	_, cg, err := s.group(key, group)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := []ConsumerInfo{}
+	for _, c := range cg.sortedConsumers() {
+		result = append(result, c.info(now))
+	}
+	return result, nil
+}
@@ -0,0 +1,297 @@
+package store
+
+import "sort"
+
+// streamNode 是流中最多 streamNodeMaxEntries 个连续条目组成的块，对应 Redis 中 rax 节点上的 listpack
+// 块内所有条目的字段和值依次保存在 data 中；字段名及其顺序与块的 master 字段（第一个条目的字段名）
+// 相同的条目只保存值。条目被删除时只标记为墓碑，块中没有未删除的条目时整块移除
+type streamNode struct {
+	masterFields []string
+	entries      []nodeEntry
+	data         []string
+	live         int // 未删除的条目数
+}
+
+// nodeEntry 是块中的一个条目
+type nodeEntry struct {
+	id         StreamID
+	offset     int32 // 在块的 data 中的起始位置
+	size       int32 // 在块的 data 中占用的元素个数
+	sameFields bool  // 字段名与 master 字段相同，data 中只保存值
+	deleted    bool
+}
+
+// newStreamNode 创建块，fields 中的字段名成为块的 master 字段
+func newStreamNode(fields []string) *streamNode {
+	n := &streamNode{masterFields: make([]string, 0, len(fields)/2)}
+	for i := 0; i < len(fields); i += 2 {
+		n.masterFields = append(n.masterFields, fields[i])
+	}
+	return n
+}
+
+// hasMasterFields 判断字段名及其顺序是否与 master 字段相同
+func (n *streamNode) hasMasterFields(fields []string) bool {
+	if len(fields) != len(n.masterFields)*2 {
+		return false
+	}
+	for i, field := range n.masterFields {
+		if fields[i*2] != field {
+			return false
+		}
+	}
+	return true
+}
+
+// add 在块的末尾添加条目，fields 为字段和值交替排列
+func (n *streamNode) add(id StreamID, fields []string) {
+	e := nodeEntry{id: id, offset: int32(len(n.data)), sameFields: n.hasMasterFields(fields)}
+	if e.sameFields {
+		for i := 1; i < len(fields); i += 2 {
+			n.data = append(n.data, fields[i])
+		}
+	} else {
+		n.data = append(n.data, fields...)
+	}
+	e.size = int32(len(n.data)) - e.offset
+	n.entries = append(n.entries, e)
+	n.live++
+}
+
+// entry 还原块中的条目
+// data 中已写入的元素不会再被修改，字段名不同的条目可以直接共享 data
+func (n *streamNode) entry(e *nodeEntry) StreamEntry {
+	data := n.data[e.offset : e.offset+e.size : e.offset+e.size]
+	if !e.sameFields {
+		return StreamEntry{ID: e.id, Fields: data}
+	}
+	fields := make([]string, 0, len(data)*2)
+	for i, field := range n.masterFields {
+		fields = append(fields, field, data[i])
+	}
+	return StreamEntry{ID: e.id, Fields: fields}
+}
+
+func (n *streamNode) firstID() StreamID {
+	return n.entries[0].id
+}
+
+func (n *streamNode) lastID() StreamID {
+	return n.entries[len(n.entries)-1].id
+}
+
+// append 在流的末尾添加条目，最后一个块已满时新建块
+func (st *stream) append(id StreamID, fields []string) {
+	if len(st.nodes) == 0 || len(st.nodes[len(st.nodes)-1].entries) >= streamNodeMaxEntries {
+		st.nodes = append(st.nodes, newStreamNode(fields))
+	}
+	st.nodes[len(st.nodes)-1].add(id, fields)
+	st.length++
+}
+
+// removeNode 移除第 i 个块，裁剪总是移除第一个块，不需要移动其余的块
+func (st *stream) removeNode(i int) {
+	if i == 0 {
+		st.nodes[0] = nil
+		st.nodes = st.nodes[1:]
+		return
+	}
+	copy(st.nodes[i:], st.nodes[i+1:])
+	st.nodes[len(st.nodes)-1] = nil
+	st.nodes = st.nodes[:len(st.nodes)-1]
+}
+
+// seek 返回第一个 ID 不小于 id 的条目（包括墓碑）所在的块和在块中的位置
+// 块按 ID 排列，先二分查找块再在块内二分查找；不存在时返回 len(st.nodes)
+func (st *stream) seek(id StreamID) (int, int) {
+	ni := sort.Search(len(st.nodes), func(i int) bool { return st.nodes[i].lastID().Compare(id) >= 0 })
+	if ni == len(st.nodes) {
+		return ni, 0
+	}
+	entries := st.nodes[ni].entries
+	return ni, sort.Search(len(entries), func(i int) bool { return entries[i].id.Compare(id) >= 0 })
+}
+
+// seekLast 返回最后一个 ID 不大于 id 的条目（包括墓碑）所在的块和在块中的位置，不存在时块为 -1
+func (st *stream) seekLast(id StreamID) (int, int) {
+	ni := sort.Search(len(st.nodes), func(i int) bool { return st.nodes[i].firstID().Compare(id) > 0 }) - 1
+	if ni < 0 {
+		return ni, 0
+	}
+	entries := st.nodes[ni].entries
+	return ni, sort.Search(len(entries), func(i int) bool { return entries[i].id.Compare(id) > 0 }) - 1
+}
+
+// scan 从 from 开始按 ID 升序遍历未删除的条目，reverse 为 true 时从 from 开始降序遍历
+// fn 返回 false 时停止
+func (st *stream) scan(from StreamID, reverse bool, fn func(n *streamNode, e *nodeEntry) bool) {
+	if reverse {
+		ni, ei := st.seekLast(from)
+		for ; ni >= 0; ni-- {
+			n := st.nodes[ni]
+			if ei < 0 {
+				ei = len(n.entries) - 1
+			}
+			for ; ei >= 0; ei-- {
+				if e := &n.entries[ei]; !e.deleted && !fn(n, e) {
+					return
+				}
+			}
+		}
+		return
+	}
+	ni, ei := st.seek(from)
+	for ; ni < len(st.nodes); ni++ {
+		n := st.nodes[ni]
+		for ; ei < len(n.entries); ei++ {
+			if e := &n.entries[ei]; !e.deleted && !fn(n, e) {
+				return
+			}
+		}
+		ei = 0
+	}
+}
+
+// find 返回指定 ID 的条目，不存在或已删除时返回 nil
+func (st *stream) find(id StreamID) *StreamEntry {
+	ni, ei := st.seek(id)
+	if ni == len(st.nodes) {
+		return nil
+	}
+	n := st.nodes[ni]
+	if e := &n.entries[ei]; e.id == id && !e.deleted {
+		entry := n.entry(e)
+		return &entry
+	}
+	return nil
+}
+
+// remove 把条目标记为墓碑，返回条目是否存在；块中的条目全部被删除时移除整块
+func (st *stream) remove(id StreamID) bool {
+	ni, ei := st.seek(id)
+	if ni == len(st.nodes) {
+		return false
+	}
+	n := st.nodes[ni]
+	e := &n.entries[ei]
+	if e.id != id || e.deleted {
+		return false
+	}
+	e.deleted = true
+	n.live--
+	st.length--
+	if n.live == 0 {
+		st.removeNode(ni)
+	}
+	return true
+}
+
+// dropTombstonesAfter 移除末尾 ID 大于 id 的墓碑
+// XSETID 把最后添加的 ID 改小时调用，保证之后添加的条目仍然按 ID 排列
+func (st *stream) dropTombstonesAfter(id StreamID) {
+	for len(st.nodes) > 0 {
+		n := st.nodes[len(st.nodes)-1]
+		i := sort.Search(len(n.entries), func(i int) bool { return n.entries[i].id.Compare(id) > 0 })
+		n.entries = n.entries[:i]
+		if i > 0 {
+			return
+		}
+		st.removeNode(len(st.nodes) - 1)
+	}
+}
+
+// first 返回第一个未删除的条目，流为空时返回 nil
+func (st *stream) first() *StreamEntry {
+	if entries := st.rangeEntries(StreamID{}, maxStreamID, 1, false); len(entries) > 0 {
+		return &entries[0]
+	}
+	return nil
+}
+
+// last 返回最后一个未删除的条目，流为空时返回 nil
+func (st *stream) last() *StreamEntry {
+	if entries := st.rangeEntries(StreamID{}, maxStreamID, 1, true); len(entries) > 0 {
+		return &entries[0]
+	}
+	return nil
+}
+
+// rangeEntries 按 ID 顺序返回 [start, end] 区间内至多 count 个未删除的条目，count 为 0 表示不限制
+// reverse 为 true 时从 end 向 start 逆序返回
+func (st *stream) rangeEntries(start, end StreamID, count int, reverse bool) []StreamEntry {
+	result := []StreamEntry{}
+	if start.Compare(end) > 0 {
+		return result
+	}
+	from := start
+	if reverse {
+		from = end
+	}
+	st.scan(from, reverse, func(n *streamNode, e *nodeEntry) bool {
+		if (!reverse && e.id.Compare(end) > 0) || (reverse && e.id.Compare(start) < 0) {
+			return false
+		}
+		result = append(result, n.entry(e))
+		return count == 0 || len(result) < count
+	})
+	return result
+}
+
+// readAfter 返回 ID 大于 start 的至多 count 个未删除的条目，count 为 0 表示不限制
+func (st *stream) readAfter(start StreamID, count int) []StreamEntry {
+	next, ok := start.next()
+	if !ok {
+		return []StreamEntry{}
+	}
+	return st.rangeEntries(next, maxStreamID, count, false)
+}
+
+// shouldTrim 判断能否移除 ID 不大于 id 的条目，remaining 为移除后剩余的条目数
+func shouldTrim(opts TrimOptions, remaining int, id StreamID) bool {
+	switch opts.Strategy {
+	case TrimMaxLen:
+		return remaining >= opts.MaxLen
+	case TrimMinID:
+		return id.Compare(opts.MinID) < 0
+	default:
+		return false
+	}
+}
+
+// trim 从头部裁剪条目，返回移除的条目数
+// 先移除块中最后一个条目也满足条件的整块，近似裁剪到此为止；精确裁剪再逐个移除第一个块中的条目
+func (st *stream) trim(opts TrimOptions) int {
+	removed := 0
+	for len(st.nodes) > 0 {
+		n := st.nodes[0]
+		if !shouldTrim(opts, st.length-removed-n.live, n.lastID()) {
+			break
+		}
+		if opts.Limit > 0 && removed+n.live > opts.Limit {
+			break
+		}
+		removed += n.live
+		st.removeNode(0)
+	}
+	if !opts.Approx && len(st.nodes) > 0 {
+		// 与 Redis 一样只把条目标记为墓碑，块仍然按添加过的条目数判断是否已满
+		n := st.nodes[0]
+		for i := range n.entries {
+			e := &n.entries[i]
+			if e.deleted {
+				continue
+			}
+			if !shouldTrim(opts, st.length-removed-1, e.id) {
+				break
+			}
+			e.deleted = true
+			removed++
+			n.live--
+		}
+		if n.live == 0 {
+			st.removeNode(0)
+		}
+	}
+	st.length -= removed
+	return removed
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -11,37 +12,100 @@ import (
 )
 
 var (
-	ErrInvalidIDFormat      = errors.New("Invalid ID format")
 	ErrIDTooSmall           = errors.New("The ID specified in XADD must be greater than 0-0")
 	ErrIDNotGreaterThanLast = errors.New("The ID specified in XADD is equal or smaller than the target stream top item")
+	ErrInvalidStreamID      = errors.New("Invalid stream ID specified as stream command argument")
+	ErrStreamExhausted      = errors.New("The stream has exhausted the last possible ID, unable to add more items")
 )
 
+const (
+	// streamNodeMaxEntries 每个块最多保存的条目数，对应 Redis 的 stream-node-max-entries
+	streamNodeMaxEntries = 100
+	// DefaultTrimLimit 近似裁剪未指定 LIMIT 时单次最多移除的条目数
+	DefaultTrimLimit = 100 * streamNodeMaxEntries
+)
+
+// TrimStrategy 流的裁剪策略
+type TrimStrategy int
+
+const (
+	TrimNone TrimStrategy = iota
+	TrimMaxLen
+	TrimMinID
+)
+
+// TrimOptions 表示 XADD 与 XTRIM 的裁剪选项
+type TrimOptions struct {
+	Strategy TrimStrategy
+	MaxLen   int
+	MinID    StreamID
+	Approx   bool // ~：只整块移除条目
+	Limit    int  // 单次最多移除的条目数，0 表示不限制
+}
+
+// XAddOptions 表示 XADD 的选项
+type XAddOptions struct {
+	NoMkStream bool
+	Trim       TrimOptions
+}
+
 // StreamOps 定义stream流操作接口
 type StreamOps interface {
-	Exists(key string) bool
-	AddEntry(key, entryID string, fields map[string]string) (string, error)
-	GetRange(key string, startID string, endID string) ([]StreamEntry, error)
-	ReadStreams(keys, startIDs []string) (map[string][]StreamEntry, error)
-	ReadStreamsBlocking(keys, startIDs []string, timeout time.Duration) (map[string][]StreamEntry, error)
+	KeyOps
+	AddEntry(key, entryID string, fields []string, opts XAddOptions) (StreamID, error)
+	Len(key string) int
+	DeleteEntries(key string, ids []string) (int, error)
+	Trim(key string, opts TrimOptions) int
+	SetID(key string, opts SetIDOptions) error
+	CreateGroup(key, group, id string, mkStream bool, entriesRead int64) error
+	SetGroupID(key, group, id string, entriesRead int64) error
+	DestroyGroup(key, group string) (bool, error)
+	CreateConsumer(key, group, consumer string) (bool, error)
+	DeleteConsumer(key, group, consumer string) (int, error)
+	ReadGroup(keys, ids []string, group, consumer string, count int, noAck bool) (map[string][]StreamEntry, map[string]GroupDelivery, error)
+	ReadGroupBlocking(ctx context.Context, keys []string, group, consumer string, count int, noAck bool, timeout time.Duration) (map[string][]StreamEntry, map[string]GroupDelivery, error)
+	Ack(key, group string, ids []string) (int, error)
+	PendingSummary(key, group string) (PendingSummary, error)
+	PendingRange(key, group string, opts PendingRangeOptions) ([]PendingInfo, error)
+	Claim(key, group, consumer string, ids []StreamID, opts ClaimOptions) (ClaimResult, error)
+	AutoClaim(key, group, consumer string, start StreamID, count int, opts ClaimOptions) (ClaimResult, error)
+	StreamInfo(key string, full bool, count int) (StreamInfo, error)
+	GroupsInfo(key string) ([]GroupInfo, error)
+	ConsumersInfo(key, group string) ([]ConsumerInfo, error)
+	GetRange(key, startID, endID string, count int, reverse bool) ([]StreamEntry, error)
+	ReadStreams(keys, startIDs []string, count int) (map[string][]StreamEntry, error)
+	ReadStreamsBlocking(ctx context.Context, keys, startIDs []string, count int, timeout time.Duration) (map[string][]StreamEntry, error)
 }
 
 // StreamEntry 表示流中的一个条目
 type StreamEntry struct {
-	ID     string
-	Fields map[string]string
+	ID     StreamID
+	Fields []string // 字段和值按添加时的顺序交替排列，可以有重复的字段
+}
+
+// stream 表示一个流，条目按 ID 升序保存在块中，块按 ID 排列
+// 新条目总是追加在最后一个块，裁剪总是从第一个块开始，所以有序的块切片就能提供二分查找的索引
+type stream struct {
+	nodes  []*streamNode
+	length int      // 未删除的条目数
+	lastID StreamID // 最后添加的 ID，条目被删除或裁剪后也不回退；从未添加过条目时为 0-0
+
+	entriesAdded int64                     // 流创建以来添加过的条目总数
+	maxDeletedID StreamID                  // XDEL 删除过的最大 ID，从未删除时为 0-0
+	groups       map[string]*consumerGroup // 消费者组
 }
 
 // StreamStore 存储流数据
 type StreamStore struct {
 	sync.RWMutex
-	streams map[string][]StreamEntry
-	waiters map[string][]chan struct{} // 等待通道映射
+	streams map[string]*stream
+	blocked blockedClients // 阻塞的 XREAD 和 XREADGROUP 客户端
 }
 
 func NewStreamStore() *StreamStore {
 	return &StreamStore{
-		streams: make(map[string][]StreamEntry),
-		waiters: make(map[string][]chan struct{}),
+		streams: make(map[string]*stream),
+		blocked: make(blockedClients),
 	}
 }
 
@@ -53,326 +117,302 @@ func (s *StreamStore) Exists(key string) bool {
 	return exists
 }
 
-// parseID 解析ID字符串为毫秒时间和序列号
-func parseID(id string) (millis int64, seq int64, err error) {
-	parts := strings.Split(id, "-")
-	if len(parts) != 2 {
-		return 0, 0, ErrInvalidIDFormat
-	}
-	millis, err = strconv.ParseInt(parts[0], 10, 64)
-	if err != nil {
-		return 0, 0, ErrInvalidIDFormat
-	}
-	seq, err = strconv.ParseInt(parts[1], 10, 64)
-	if err != nil {
-		return 0, 0, ErrInvalidIDFormat
-	}
-	return millis, seq, nil
+// Delete 删除流，返回流是否存在
+func (s *StreamStore) Delete(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, exists := s.streams[key]
+	delete(s.streams, key)
+	return exists
 }
 
-// validateID 验证新ID是否有效
-func (s *StreamStore) validateID(key, newID string) error {
-	// 解析新ID
-	newMillis, newSeq, err := parseID(newID)
-	if err != nil {
-		return err
-	}
-	// 检查是否大于0-0
-	if newMillis < 0 || (newMillis == 0 && newSeq == 0) {
-		return ErrIDTooSmall
-	}
-	// 获取流的最后一个条目
-	entries, exists := s.streams[key]
-	if !exists || len(entries) == 0 {
-		return nil // 空流，任何大于0-0的ID都有效
-	}
-	lastEntry := entries[len(entries)-1]
-	lastMillis, lastSeq, err := parseID(lastEntry.ID)
-	if err != nil {
-		return fmt.Errorf("invalid last entry ID: %s", lastEntry.ID)
-	}
-	// 验证新ID大于最后一个ID
-	if newMillis < lastMillis || (newMillis == lastMillis && newSeq <= lastSeq) {
-		return ErrIDNotGreaterThanLast
+// generateID 按 Redis 的方式为 "*"（autoMs）和 "ms-*" 生成 ID，last 为流最后添加的 ID
+// "*" 使用 max(当前时间, last 的毫秒时间)，时钟回拨时沿用 last 的毫秒时间并递增序列号；
+// "ms-*" 在毫秒时间与 last 相同时递增序列号，否则序列号从 0 开始
+func generateID(last StreamID, ms uint64, autoMs bool) (StreamID, error) {
+	switch {
+	case autoMs && ms > last.Ms:
+		return StreamID{Ms: ms}, nil
+	case autoMs:
+		// 调用者已经排除了 last 是最大 ID 的情况
+		next, _ := last.next()
+		return next, nil
+	case ms == last.Ms && last.Seq == math.MaxUint64:
+		return StreamID{}, ErrIDNotGreaterThanLast
+	case ms == last.Ms:
+		return StreamID{Ms: ms, Seq: last.Seq + 1}, nil
+	default:
+		return StreamID{Ms: ms}, nil
 	}
-	return nil
 }
 
-// generateNextSequence 生成下一个序列号
-func (s *StreamStore) generateNextSequence(key string, newMillis int64) (int64, error) {
-	entries, exists := s.streams[key]
-	if !exists || len(entries) == 0 {
-		// 流为空，特殊处理 0 时间部分
-		if newMillis == 0 {
-			return 1, nil // 0-0 不允许，所以从 1 开始
+// AddEntry 向指定流添加条目（不存在则创建）并按选项裁剪，fields 为字段和值交替排列
+// entryID 可以是 "*"、"ms-*" 或者完整的 ID；设置 NoMkStream 且流不存在时不添加，返回 0-0
+func (s *StreamStore) AddEntry(key, entryID string, fields []string, opts XAddOptions) (StreamID, error) {
+	var id StreamID
+	autoMs, autoSeq := entryID == "*", false
+	if !autoMs {
+		var err error
+		if msPart, seqPart, _ := strings.Cut(entryID, "-"); seqPart == "*" {
+			autoSeq = true
+			if id.Ms, err = strconv.ParseUint(msPart, 10, 64); err != nil {
+				return StreamID{}, ErrInvalidStreamID
+			}
+		} else if id, err = ParseStreamID(entryID); err != nil {
+			return StreamID{}, err
+		}
+		if !autoSeq && id.IsZero() {
+			return StreamID{}, ErrIDTooSmall
 		}
-		return 0, nil
-	}
-	// 获取最后一个条目
-	lastEntry := entries[len(entries)-1]
-	lastMillis, lastSeq, err := parseID(lastEntry.ID)
-	if err != nil {
-		return 0, fmt.Errorf("Invalid last entry ID: %s", lastEntry.ID)
-	}
-	switch {
-	case newMillis > lastMillis:
-		return 0, nil // 新时间部分，序列号从 0 开始
-	case newMillis == lastMillis:
-		return lastSeq + 1, nil // 相同时间部分，序列号递增
-	default:
-		return 0, ErrIDNotGreaterThanLast // 新时间部分小于最后条目
 	}
-}
 
-// AddEntry 向指定流添加条目（不存在则创建）
-func (s *StreamStore) AddEntry(key, entryID string, fields map[string]string) (string, error) {
 	s.Lock()
 	defer s.Unlock()
 
-	finalID := entryID
-
-	// 处理完全自动生成序列号的情况 (*)
-	if entryID == "*" {
-		millisNow := time.Now().UnixMilli()
-		// 生成序列号
-		seq, err := s.generateNextSequence(key, millisNow)
-		if err != nil {
-			return "", nil
-		}
-		finalID = fmt.Sprintf("%d-%d", millisNow, seq)
-	} else if parts := strings.Split(entryID, "-"); len(parts) == 2 && parts[1] == "*" {
-		// 处理部分自动生成序列号 (millis-*)
-		millisPart, err := strconv.ParseInt(parts[0], 10, 64)
-		if err != nil {
-			return "", ErrInvalidIDFormat
+	st, exists := s.streams[key]
+	if !exists && opts.NoMkStream {
+		return StreamID{}, nil
+	}
+	var lastID StreamID
+	if exists {
+		lastID = st.lastID
+	}
+	if lastID == maxStreamID {
+		return StreamID{}, ErrStreamExhausted
+	}
+	if autoMs || autoSeq {
+		if autoMs {
+			id.Ms = uint64(time.Now().UnixMilli())
 		}
-		// 生成序列号
-		seq, err := s.generateNextSequence(key, millisPart)
-		if err != nil {
-			return "", err
+		var err error
+		if id, err = generateID(lastID, id.Ms, autoMs); err != nil {
+			return StreamID{}, err
 		}
-		finalID = fmt.Sprintf("%d-%d", millisPart, seq)
 	}
-
-	// 验证ID
-	if err := s.validateID(key, finalID); err != nil {
-		return "", err
+	// 新 ID 必须大于最后添加的 ID
+	if id.Compare(lastID) <= 0 {
+		return StreamID{}, ErrIDNotGreaterThanLast
 	}
 
 	// 如果流不存在，创建新流
-	if _, exists := s.streams[key]; !exists {
-		s.streams[key] = make([]StreamEntry, 0)
-	}
-	entry := StreamEntry{
-		ID:     finalID,
-		Fields: fields,
-	}
-	s.streams[key] = append(s.streams[key], entry)
-
-	// 唤醒该键的第一个等待者
-	if waiters, ok := s.waiters[key]; ok && len(waiters) > 0 {
-		waiter := waiters[0]         // 获取最先等待的客户端
-		s.waiters[key] = waiters[1:] // 移除已唤醒的客户端
-		if len(s.waiters[key]) == 0 {
-			delete(s.waiters, key)
-		}
-		close(waiter) // 唤醒客户端（非阻塞）
+	if !exists {
+		st = &stream{}
+		s.streams[key] = st
 	}
+	st.append(id, fields)
+	st.lastID = id
+	st.entriesAdded++
+	st.trim(opts.Trim)
+
+	// 新条目不会被读取取走，为阻塞在该键上的所有客户端读取
+	s.blocked.serveEach(key)
 
-	return finalID, nil
+	return id, nil
 }
 
-// normalizeRangeID 规范化范围ID
-func normalizeRangeID(id string, isEnd bool) (millis int64, seq int64, err error) {
-	// 处理特殊值：- 表示最小ID
-	if id == "-" {
-		return 0, 0, nil
+// Len 返回流中未删除的条目数
+func (s *StreamStore) Len(key string) int {
+	s.RLock()
+	defer s.RUnlock()
+	if st, exists := s.streams[key]; exists {
+		return st.length
 	}
+	return 0
+}
 
-	// 处理特殊值：+ 表示最大ID
-	if id == "+" {
-		return math.MaxInt64, math.MaxInt64, nil
+// DeleteEntries 删除指定ID的条目，返回实际删除的数量
+// 条目只被标记为墓碑，流为空时也保留键
+func (s *StreamStore) DeleteEntries(key string, ids []string) (int, error) {
+	parsed, err := parseStreamIDs(ids)
+	if err != nil {
+		return 0, err
 	}
-
-	// 如果ID不包含'-'，则添加"-0"后缀（对于结束ID使用最大序列号）
-	if !strings.Contains(id, "-") {
-		parsedMillis, err := strconv.ParseInt(id, 10, 64)
-		if err != nil {
-			return 0, 0, ErrInvalidIDFormat
+	s.Lock()
+	defer s.Unlock()
+	st, exists := s.streams[key]
+	if !exists {
+		return 0, nil
+	}
+	deleted := 0
+	for _, id := range parsed {
+		if !st.remove(id) {
+			continue
+		}
+		if id.Compare(st.maxDeletedID) > 0 {
+			st.maxDeletedID = id
 		}
+		deleted++
+	}
+	return deleted, nil
+}
 
-		if isEnd {
-			return parsedMillis, math.MaxInt64, nil
+// parseStreamIDs 解析一组 ID 参数，任一 ID 无效时返回错误
+func parseStreamIDs(ids []string) ([]StreamID, error) {
+	parsed := make([]StreamID, len(ids))
+	for i, id := range ids {
+		var err error
+		if parsed[i], err = ParseStreamID(id); err != nil {
+			return nil, err
 		}
-		return parsedMillis, 0, nil
 	}
-	return parseID(id)
+	return parsed, nil
 }
 
-// GetRange 获取指定范围内的条目
-func (s *StreamStore) GetRange(key string, startID string, endID string) ([]StreamEntry, error) {
-	s.RLock()
-	defer s.RUnlock()
+// Trim 按选项裁剪流，返回移除的条目数
+func (s *StreamStore) Trim(key string, opts TrimOptions) int {
+	s.Lock()
+	defer s.Unlock()
+	st, exists := s.streams[key]
+	if !exists {
+		return 0
+	}
+	return st.trim(opts)
+}
+
+// SetIDOptions 是 XSETID 的参数
+type SetIDOptions struct {
+	LastID       StreamID
+	EntriesAdded int64     // -1 表示不修改
+	MaxDeletedID *StreamID // nil 表示不修改
+}
 
-	entries, exists := s.streams[key]
+// SetID 修改流最后添加的 ID 以及添加过的条目数和删除过的最大 ID（XSETID），流不存在时返回 ErrNoSuchKey
+// 新的 ID 可以小于原来的 ID，但不能小于最后一个未删除的条目
+func (s *StreamStore) SetID(key string, opts SetIDOptions) error {
+	s.Lock()
+	defer s.Unlock()
+	st, exists := s.streams[key]
 	if !exists {
-		return []StreamEntry{}, nil
+		return ErrNoSuchKey
+	}
+	if st.length > 0 {
+		if opts.LastID.Compare(st.last().ID) < 0 {
+			return fmt.Errorf("The ID specified in XSETID is smaller than the target stream top item")
+		}
+		if opts.EntriesAdded != -1 && int64(st.length) > opts.EntriesAdded {
+			return fmt.Errorf("The entries_added specified in XSETID is smaller than the target stream length")
+		}
+	}
+	st.lastID = opts.LastID
+	st.dropTombstonesAfter(opts.LastID)
+	if opts.EntriesAdded != -1 {
+		st.entriesAdded = opts.EntriesAdded
 	}
-	startMillis, startSeq, err := normalizeRangeID(startID, false)
+	if opts.MaxDeletedID != nil {
+		st.maxDeletedID = *opts.MaxDeletedID
+	}
+	return nil
+}
+
+// GetRange 获取指定范围内的条目（XRANGE/XREVRANGE），count 为 0 表示不限制
+// 端点支持 "-"、"+"、省略序列号以及 "(" 前缀的开区间
+func (s *StreamStore) GetRange(key, startID, endID string, count int, reverse bool) ([]StreamEntry, error) {
+	start, err := ParseRangeID(startID, false)
 	if err != nil {
 		return nil, err
 	}
-	endMillis, endSeq, err := normalizeRangeID(endID, false)
+	end, err := ParseRangeID(endID, true)
 	if err != nil {
 		return nil, err
 	}
-	result := []StreamEntry{}
-	for _, entry := range entries {
-		entryMillis, entrySeq, err := parseID(entry.ID)
-		if err != nil {
-			return nil, fmt.Errorf("Invalid entry ID: %s", entry.ID)
-		}
-		switch {
-		case entryMillis < startMillis:
-			continue
-		case entryMillis == startMillis && entrySeq < startSeq:
-			continue
-		case entryMillis > endMillis:
-			break
-		case entryMillis == endMillis && entrySeq > endSeq:
-			break
-		default:
-			result = append(result, entry)
-		}
+	s.RLock()
+	defer s.RUnlock()
+	st, exists := s.streams[key]
+	if !exists {
+		return []StreamEntry{}, nil
 	}
-	return result, nil
+	return st.rangeEntries(start, end, count, reverse), nil
 }
 
-// ReadStreams 实现XREAD的多流查询
-func (s *StreamStore) ReadStreams(keys, startIDs []string) (map[string][]StreamEntry, error) {
-	s.RLock()
-	defer s.RUnlock()
+// resolveReadID 把 XREAD 的 ID 解析为不包含的起点，st 为 nil 表示流不存在
+// "$" 表示最后添加的 ID；"+" 表示从最后一个条目开始读取（包含该条目），流为空时与 "$" 相同
+func (st *stream) resolveReadID(id string) (StreamID, error) {
+	switch {
+	case st == nil && (id == "$" || id == "+"):
+		return StreamID{}, nil
+	case id == "$":
+		return st.lastID, nil
+	case id == "+":
+		if last := st.last(); last != nil {
+			// 条目 ID 大于 0-0，开区间起点就是它前面紧邻的 ID
+			start, _ := last.ID.prev()
+			return start, nil
+		}
+		return st.lastID, nil
+	default:
+		return ParseStreamID(id)
+	}
+}
 
+// readStreamsLocked 读取每个流中起点之后的条目，只有有条目的流出现在结果中
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *StreamStore) readStreamsLocked(keys []string, starts []StreamID, count int) map[string][]StreamEntry {
 	result := make(map[string][]StreamEntry)
 	for i, key := range keys {
-		startID := startIDs[i]
-		// 获取从startID到最大ID的条目
-		entries, err := s.GetRange(key, startID, "+")
-		if err != nil {
-			return nil, fmt.Errorf("error reading stream %s: %v", key, err)
-		}
-		// 过滤掉ID小于或等于startID的条目（XREAD要求严格大于）
-		startMillis, startSeq, err := normalizeRangeID(startID, false)
-		if err != nil {
-			return nil, fmt.Errorf("invalid start ID %s for stream %s: %v", startID, key, err)
-		}
-		filteredEntries := make([]StreamEntry, 0)
-		for _, entry := range entries {
-			entryMillis, entrySeq, err := parseID(entry.ID)
-			if err != nil {
-				return nil, fmt.Errorf("invalid entry ID %s in stream %s: %v", entry.ID, key, err)
-			}
-			// 仅保留严格大于startID的条目
-			if entryMillis > startMillis || (entryMillis == startMillis && entrySeq > startSeq) {
-				filteredEntries = append(filteredEntries, entry)
-			}
+		st, exists := s.streams[key]
+		if !exists {
+			continue
 		}
-		// 仅当有有效条目时添加结果
-		if len(filteredEntries) > 0 {
-			result[key] = filteredEntries
+		if entries := st.readAfter(starts[i], count); len(entries) > 0 {
+			result[key] = entries
 		}
 	}
-	return result, nil
+	return result
 }
 
-// resolveStartID 解析起始ID，处理特殊值'$'
-func (s *StreamStore) resolveStartID(key, startID string) (string, error) {
-	if startID == "$" {
-		s.RLock()
-		defer s.RUnlock()
-
-		if entries, exists := s.streams[key]; exists && len(entries) > 0 {
-			// 返回最后一个条目的ID
-			return entries[len(entries)-1].ID, nil
+// resolveReadIDs 解析所有流的起始 ID
+// 必须在调用者持有读锁或写锁的情况下调用
+func (s *StreamStore) resolveReadIDs(keys, ids []string) ([]StreamID, error) {
+	starts := make([]StreamID, len(keys))
+	for i, key := range keys {
+		var err error
+		if starts[i], err = s.streams[key].resolveReadID(ids[i]); err != nil {
+			return nil, err
 		}
-		// 流为空时使用"0-0"
-		return "0-0", nil
 	}
-	return startID, nil
+	return starts, nil
 }
 
-// ReadStreamsBlocking 阻塞读取大于指定ID的条目
-func (s *StreamStore) ReadStreamsBlocking(keys, startsID []string, timeout time.Duration) (map[string][]StreamEntry, error) {
-	// XREAD BLOCK只支持单个streamKey
-	key, startID := keys[0], startsID[0]
-	// 解析特殊ID'$'
-	resolvedID, err := s.resolveStartID(key, startID)
+// ReadStreams 实现XREAD的多流查询，返回每个流中 ID 大于起始 ID 的至多 count 个条目
+func (s *StreamStore) ReadStreams(keys, startIDs []string, count int) (map[string][]StreamEntry, error) {
+	s.RLock()
+	defer s.RUnlock()
+	starts, err := s.resolveReadIDs(keys, startIDs)
 	if err != nil {
 		return nil, err
 	}
-	s.Lock()
-	// 创建等待通道并加入队列
-	ch := make(chan struct{})
-	s.waiters[key] = append(s.waiters[key], ch)
-	s.Unlock()
+	return s.readStreamsLocked(keys, starts, count), nil
+}
 
-	// 处理超时
-	var timeoutCh <-chan time.Time
-	if timeout > 0 {
-		timeoutCh = time.After(timeout)
-	} else {
-		// 0 表示无限阻塞
-		timeoutCh = make(chan time.Time) // 永不超时的通道
+// ReadStreamsBlocking 阻塞读取大于指定ID的条目
+// 任一流已有新条目时立即返回，否则阻塞直到某个流添加了条目，只返回该流的条目；超时返回空结果
+// ctx 被取消时（连接断开或 CLIENT UNBLOCK）放弃等待并返回取消原因
+func (s *StreamStore) ReadStreamsBlocking(ctx context.Context, keys, startIDs []string, count int, timeout time.Duration) (map[string][]StreamEntry, error) {
+	s.Lock()
+	starts, err := s.resolveReadIDs(keys, startIDs)
+	if err != nil {
+		s.Unlock()
+		return nil, err
 	}
-
-	select {
-	case <-ch: // 被唤醒
-		s.RLock()
-		defer s.RUnlock()
-
-		// 再次检查流状态
-		entries, exists := s.streams[key]
-		if !exists {
-			return map[string][]StreamEntry{}, nil
-		}
-		result := make(map[string][]StreamEntry)
-		// 获取所有大于startID的条目
-		resultEntry := []StreamEntry{}
-		startMillis, startSeq, err := parseID(resolvedID)
-		if err != nil {
-			return nil, err
-		}
-		for _, entry := range entries {
-			entryMillis, entrySeq, err := parseID(entry.ID)
-			if err != nil {
-				return nil, err
-			}
-
-			if entryMillis > startMillis || (entryMillis == startMillis && entrySeq > startSeq) {
-				resultEntry = append(resultEntry, entry)
-			}
-		}
-		result[key] = resultEntry
+	if result := s.readStreamsLocked(keys, starts, count); len(result) > 0 {
+		s.Unlock()
 		return result, nil
-	case <-timeoutCh: // 超时
-		// 从等待队列中移除自己
-		s.Lock()
-		defer s.Unlock()
-
-		if waiters, ok := s.waiters[key]; ok {
-			for i, waiter := range waiters {
-				if waiter == ch {
-					// 从切片中移除通道
-					s.waiters[key] = append(waiters[:i], waiters[i+1:]...)
-					// 清理空队列
-					if len(s.waiters[key]) == 0 {
-						delete(s.waiters, key)
-					}
-					break
-				}
+	}
+	var served []StreamEntry
+	w := s.blocked.block(keys, func(key string) bool {
+		for i, k := range keys {
+			if k == key {
+				served = s.streams[key].readAfter(starts[i], count)
+				return len(served) > 0
 			}
 		}
+		return false
+	})
+	s.Unlock()
 
-		return map[string][]StreamEntry{}, nil
+	key, err := s.blocked.wait(ctx, s, w, timeout)
+	if err != nil || key == "" {
+		return map[string][]StreamEntry{}, err
 	}
+	return map[string][]StreamEntry{key: served}, nil
 }
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// benchStreamSizes 是基准测试中流的条目数，最大的规模在 -short 下跳过
+var benchStreamSizes = []int{10_000, 1_000_000, 10_000_000}
+
+// benchStreams 缓存已构建的流，同一规模只构建一次
+var benchStreams = map[int]*StreamStore{}
+
+const benchKey = "bench"
+
+// benchStream 返回包含 n 个条目的流，ID 为 1-1 到 n-1
+func benchStream(b *testing.B, n int) *StreamStore {
+	if n > 1_000_000 && testing.Short() {
+		b.Skip("skipping large stream in short mode")
+	}
+	if ss, ok := benchStreams[n]; ok {
+		return ss
+	}
+	ss := NewStreamStore()
+	fields := []string{"sensor", "1", "temperature", "21.5"}
+	for i := 1; i <= n; i++ {
+		if _, err := ss.AddEntry(benchKey, strconv.Itoa(i)+"-1", fields, XAddOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchStreams[n] = ss
+	return ss
+}
+
+// benchStreamRead 在每种规模的流上从随机位置开始执行 read，避免只测量流的头部
+func benchStreamRead(b *testing.B, read func(ss *StreamStore, id string)) {
+	for _, n := range benchStreamSizes {
+		b.Run(fmt.Sprintf("entries=%d", n), func(b *testing.B) {
+			ss := benchStream(b, n)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				read(ss, strconv.Itoa(rand.Intn(n)+1)+"-0")
+			}
+		})
+	}
+}
+
+func BenchmarkXRange(b *testing.B) {
+	benchStreamRead(b, func(ss *StreamStore, id string) {
+		ss.GetRange(benchKey, id, "+", 10, false)
+	})
+}
+
+func BenchmarkXRevRange(b *testing.B) {
+	benchStreamRead(b, func(ss *StreamStore, id string) {
+		ss.GetRange(benchKey, "-", id, 10, true)
+	})
+}
+
+func BenchmarkXRead(b *testing.B) {
+	benchStreamRead(b, func(ss *StreamStore, id string) {
+		ss.ReadStreams([]string{benchKey}, []string{id}, 10)
+	})
+}
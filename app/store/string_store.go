@@ -9,6 +9,7 @@ import (
 
 // StringOps 定义字符串操作接口
 type StringOps interface {
+	KeyOps
 	SetString(key, value string, expiresAt time.Time, hasExpiry bool)
 	GetString(key string) (string, bool)
 	Increment(key string) (int, error)
@@ -50,6 +51,23 @@ func (s *StringStore) checkString(key string) (StringEntry, bool) {
 	return entry, true
 }
 
+// Exists 是否存在未过期的key
+func (s *StringStore) Exists(key string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	entry, exists := s.m[key]
+	return exists && !(entry.HasExpiry && time.Now().After(entry.ExpiresAt))
+}
+
+// Delete 删除key，返回key是否存在
+func (s *StringStore) Delete(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, exists := s.checkString(key)
+	delete(s.m, key)
+	return exists
+}
+
 // SetString 设置键值对，并可设置过期时间
 func (s *StringStore) SetString(key, value string, expiresAt time.Time, hasExpiry bool) {
 	s.Lock()
@@ -0,0 +1,551 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrScoreNaN = errors.New("resulting score is not a number (NaN)")
+
+// ScoreMember 表示有序集合中的一个成员及其分数
+type ScoreMember struct {
+	Member string
+	Score  float64
+}
+
+// ZAddOptions 对应 ZADD 的 NX/XX/GT/LT/INCR 选项
+type ZAddOptions struct {
+	NX, XX, GT, LT bool
+	Incr           bool // 将分数加到已有分数上（只能有一个成员）
+}
+
+// ZAddResult 保存 ZADD 的执行结果
+type ZAddResult struct {
+	Added   int     // 新增的成员数
+	Changed int     // 新增或分数被修改的成员数（CH）
+	Score   float64 // INCR 模式下成员的新分数
+	Applied bool    // INCR 模式下是否实际执行（可能因 NX/XX/GT/LT 而放弃）
+}
+
+// RangeBy 表示 ZRANGE 区间的类型
+type RangeBy int
+
+const (
+	ByRank RangeBy = iota
+	ByScore
+	ByLex
+)
+
+// RangeSpec 描述 ZRANGE 系列命令的查询区间
+type RangeSpec struct {
+	By          RangeBy
+	Start, Stop int // ByRank 时的排名区间，支持负数索引
+	Score       ScoreRange
+	Lex         LexRange
+	Reverse     bool // 按从高到低的顺序返回，排名也从高分一端算起
+	Offset      int  // LIMIT 偏移量，仅用于 ByScore 和 ByLex
+	Count       int  // LIMIT 数量，负数表示不限
+}
+
+// ZSetOps 定义有序集合操作接口
+type ZSetOps interface {
+	KeyOps
+	Add(key string, elements []ScoreMember, opts ZAddOptions) (ZAddResult, error)
+	Score(key, member string) (float64, bool)
+	Scores(key string, members []string) ([]float64, []bool)
+	Rank(key, member string, reverse bool) (int, float64, bool)
+	Remove(key string, members []string) int
+	Card(key string) int
+	Count(key string, r ScoreRange) int
+	LexCount(key string, r LexRange) int
+	Range(key string, spec RangeSpec) []ScoreMember
+	RemoveRange(key string, spec RangeSpec) int
+	StoreElements(key string, elements []ScoreMember) int
+	Pop(keys []string, count int, max bool) (string, []ScoreMember)
+	BlockingPop(ctx context.Context, keys []string, count int, max bool, timeout time.Duration) (string, []ScoreMember, error)
+	RandomMembers(key string, count int, allowRepeats bool) []ScoreMember
+	Scan(key string, cursor uint64, match string, count int) ([]ScoreMember, uint64)
+}
+
+// zsetObject 用跳表维护顺序，用哈希表按成员查分数
+type zsetObject struct {
+	zsl  *skiplist
+	dict map[string]float64
+	scan *scanIndex // ZSCAN 使用的索引，有序集合较大时按需建立
+}
+
+func newZSetObject() *zsetObject {
+	return &zsetObject{
+		zsl:  newSkiplist(),
+		dict: make(map[string]float64),
+	}
+}
+
+// members 返回所有成员，顺序不定
+func (z *zsetObject) members() []string {
+	members := make([]string, 0, len(z.dict))
+	for member := range z.dict {
+		members = append(members, member)
+	}
+	return members
+}
+
+// remove 删除成员
+func (z *zsetObject) remove(member string) bool {
+	score, exists := z.dict[member]
+	if !exists {
+		return false
+	}
+	delete(z.dict, member)
+	z.zsl.delete(score, member)
+	z.scan.remove(member)
+	return true
+}
+
+// set 设置成员分数（新增或更新）
+func (z *zsetObject) set(member string, score float64) {
+	if cur, exists := z.dict[member]; exists {
+		if cur != score {
+			z.zsl.updateScore(cur, member, score)
+			z.dict[member] = score
+		}
+		return
+	}
+	z.zsl.insert(score, member)
+	z.dict[member] = score
+	z.scan.add(member)
+}
+
+// countInRange 返回区间内的节点数量
+func (z *zsetObject) countInRange(r rangeSpec) int {
+	first := z.zsl.firstInRange(r)
+	if first == nil {
+		return 0
+	}
+	last := z.zsl.lastInRange(r)
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+// rangeNodes 返回区间内的节点，按 spec.Reverse 指定的顺序排列
+func (z *zsetObject) rangeNodes(spec RangeSpec) []*skiplistNode {
+	next := func(x *skiplistNode) *skiplistNode {
+		if spec.Reverse {
+			return x.backward
+		}
+		return x.level[0].forward
+	}
+	if spec.By == ByRank {
+		return z.rankNodes(spec.Start, spec.Stop, spec.Reverse, next)
+	}
+	if spec.Offset < 0 {
+		return nil
+	}
+
+	var r rangeSpec = spec.Score
+	if spec.By == ByLex {
+		r = spec.Lex
+	}
+	var x *skiplistNode
+	if spec.Reverse {
+		x = z.zsl.lastInRange(r)
+	} else {
+		x = z.zsl.firstInRange(r)
+	}
+	for offset := spec.Offset; x != nil && offset > 0; offset-- {
+		x = next(x)
+	}
+	var nodes []*skiplistNode
+	for x != nil && (spec.Count < 0 || len(nodes) < spec.Count) {
+		if (spec.Reverse && !r.aboveMin(x)) || (!spec.Reverse && !r.belowMax(x)) {
+			break
+		}
+		nodes = append(nodes, x)
+		x = next(x)
+	}
+	return nodes
+}
+
+// rankNodes 返回排名在 [start, stop] 内的节点，负数索引从末尾算起
+func (z *zsetObject) rankNodes(start, stop int, reverse bool, next func(*skiplistNode) *skiplistNode) []*skiplistNode {
+	length := z.zsl.length
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= length {
+		return nil
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	var x *skiplistNode
+	if reverse {
+		x = z.zsl.byRank(length - start)
+	} else {
+		x = z.zsl.byRank(start + 1)
+	}
+	nodes := make([]*skiplistNode, 0, stop-start+1)
+	for i := start; i <= stop; i++ {
+		nodes = append(nodes, x)
+		x = next(x)
+	}
+	return nodes
+}
+
+// ZSetStore 实现有序集合操作
+type ZSetStore struct {
+	sync.RWMutex
+	m       map[string]*zsetObject
+	blocked blockedClients // 阻塞在 BZPOPMIN/BZPOPMAX/BZMPOP 上的客户端
+}
+
+func NewZSetStore() *ZSetStore {
+	return &ZSetStore{
+		m:       make(map[string]*zsetObject),
+		blocked: make(blockedClients),
+	}
+}
+
+// Exists 是否存在key
+func (s *ZSetStore) Exists(key string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	_, exists := s.m[key]
+	return exists
+}
+
+// Delete 删除有序集合，返回key是否存在
+func (s *ZSetStore) Delete(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, exists := s.m[key]
+	delete(s.m, key)
+	return exists
+}
+
+// Add 按 ZADD 的语义添加或更新成员
+func (s *ZSetStore) Add(key string, elements []ScoreMember, opts ZAddOptions) (ZAddResult, error) {
+	s.Lock()
+	defer s.Unlock()
+	var result ZAddResult
+	zset, exists := s.m[key]
+	if !exists {
+		if opts.XX {
+			return result, nil
+		}
+		zset = newZSetObject()
+		s.m[key] = zset
+	}
+	defer func() {
+		if len(zset.dict) == 0 {
+			delete(s.m, key)
+		}
+	}()
+
+	for _, e := range elements {
+		score := e.Score
+		cur, exists := zset.dict[e.Member]
+		if exists {
+			if opts.NX {
+				continue
+			}
+			if opts.Incr {
+				score += cur
+				if math.IsNaN(score) {
+					return result, ErrScoreNaN
+				}
+			}
+			if (opts.GT && score <= cur) || (opts.LT && score >= cur) {
+				continue
+			}
+			result.Score, result.Applied = score, true
+			if score != cur {
+				zset.set(e.Member, score)
+				result.Changed++
+			}
+			continue
+		}
+		if opts.XX {
+			continue
+		}
+		zset.set(e.Member, score)
+		result.Score, result.Applied = score, true
+		result.Added++
+		result.Changed++
+	}
+	if result.Added > 0 {
+		s.blocked.serveKey(key)
+	}
+	return result, nil
+}
+
+// Score 返回成员的分数
+func (s *ZSetStore) Score(key, member string) (float64, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	zset, exists := s.m[key]
+	if !exists {
+		return 0, false
+	}
+	score, ok := zset.dict[member]
+	return score, ok
+}
+
+// Scores 批量返回成员的分数，第二个返回值标记成员是否存在
+func (s *ZSetStore) Scores(key string, members []string) ([]float64, []bool) {
+	s.RLock()
+	defer s.RUnlock()
+	scores := make([]float64, len(members))
+	found := make([]bool, len(members))
+	if zset, exists := s.m[key]; exists {
+		for i, member := range members {
+			scores[i], found[i] = zset.dict[member]
+		}
+	}
+	return scores, found
+}
+
+// Rank 返回成员的排名（从 0 开始）及分数，reverse 为 true 时按分数从高到低排名
+func (s *ZSetStore) Rank(key, member string, reverse bool) (int, float64, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	zset, exists := s.m[key]
+	if !exists {
+		return 0, 0, false
+	}
+	score, ok := zset.dict[member]
+	if !ok {
+		return 0, 0, false
+	}
+	rank := zset.zsl.rank(score, member)
+	if reverse {
+		return zset.zsl.length - rank, score, true
+	}
+	return rank - 1, score, true
+}
+
+// Remove 删除成员，返回实际删除的数量；有序集合为空时删除键
+func (s *ZSetStore) Remove(key string, members []string) int {
+	s.Lock()
+	defer s.Unlock()
+	zset, exists := s.m[key]
+	if !exists {
+		return 0
+	}
+	removed := 0
+	for _, member := range members {
+		if zset.remove(member) {
+			removed++
+		}
+	}
+	if len(zset.dict) == 0 {
+		delete(s.m, key)
+	}
+	return removed
+}
+
+// Card 返回成员数量
+func (s *ZSetStore) Card(key string) int {
+	s.RLock()
+	defer s.RUnlock()
+	if zset, exists := s.m[key]; exists {
+		return zset.zsl.length
+	}
+	return 0
+}
+
+// Count 返回分数在区间内的成员数量
+func (s *ZSetStore) Count(key string, r ScoreRange) int {
+	s.RLock()
+	defer s.RUnlock()
+	zset, exists := s.m[key]
+	if !exists {
+		return 0
+	}
+	return zset.countInRange(r)
+}
+
+// LexCount 返回字典序在区间内的成员数量
+func (s *ZSetStore) LexCount(key string, r LexRange) int {
+	s.RLock()
+	defer s.RUnlock()
+	zset, exists := s.m[key]
+	if !exists {
+		return 0
+	}
+	return zset.countInRange(r)
+}
+
+// Range 返回区间内的成员及分数
+func (s *ZSetStore) Range(key string, spec RangeSpec) []ScoreMember {
+	s.RLock()
+	defer s.RUnlock()
+	zset, exists := s.m[key]
+	if !exists {
+		return []ScoreMember{}
+	}
+	nodes := zset.rangeNodes(spec)
+	result := make([]ScoreMember, len(nodes))
+	for i, x := range nodes {
+		result[i] = ScoreMember{Member: x.member, Score: x.score}
+	}
+	return result
+}
+
+// RemoveRange 删除区间内的成员，返回删除的数量；有序集合为空时删除键
+func (s *ZSetStore) RemoveRange(key string, spec RangeSpec) int {
+	s.Lock()
+	defer s.Unlock()
+	zset, exists := s.m[key]
+	if !exists {
+		return 0
+	}
+	nodes := zset.rangeNodes(spec)
+	for _, x := range nodes {
+		zset.remove(x.member)
+	}
+	if len(zset.dict) == 0 {
+		delete(s.m, key)
+	}
+	return len(nodes)
+}
+
+// StoreElements 用给定成员替换有序集合的全部内容，成员为空时删除键；返回成员数量
+func (s *ZSetStore) StoreElements(key string, elements []ScoreMember) int {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.m, key)
+	if len(elements) == 0 {
+		return 0
+	}
+	zset := newZSetObject()
+	for _, e := range elements {
+		zset.set(e.Member, e.Score)
+	}
+	s.m[key] = zset
+	card := len(zset.dict)
+	s.blocked.serveKey(key)
+	return card
+}
+
+// pop 弹出至多 count 个分数最低（max 为 true 时最高）的成员；有序集合为空时删除键
+// 必须在调用者持有写锁的情况下调用
+func (s *ZSetStore) pop(key string, count int, max bool) []ScoreMember {
+	zset, exists := s.m[key]
+	if !exists || count <= 0 {
+		return nil
+	}
+	var result []ScoreMember
+	for len(result) < count && zset.zsl.length > 0 {
+		x := zset.zsl.header.level[0].forward
+		if max {
+			x = zset.zsl.tail
+		}
+		result = append(result, ScoreMember{Member: x.member, Score: x.score})
+		zset.remove(x.member)
+	}
+	if len(zset.dict) == 0 {
+		delete(s.m, key)
+	}
+	return result
+}
+
+// Pop 从第一个非空的有序集合中弹出至多 count 个分数最低（max 为 true 时最高）的成员
+// 返回成员所在的键，所有键都为空时返回空字符串
+func (s *ZSetStore) Pop(keys []string, count int, max bool) (string, []ScoreMember) {
+	s.Lock()
+	defer s.Unlock()
+	for _, key := range keys {
+		if popped := s.pop(key, count, max); len(popped) > 0 {
+			return key, popped
+		}
+	}
+	return "", nil
+}
+
+// BlockingPop 与 Pop 相同，但所有键都为空时阻塞直到有成员加入，timeout 0 表示无限阻塞
+// 超时返回空字符串；ctx 被取消时返回取消原因，取消前已被服务时仍返回弹出的成员
+func (s *ZSetStore) BlockingPop(ctx context.Context, keys []string, count int, max bool, timeout time.Duration) (string, []ScoreMember, error) {
+	s.Lock()
+	for _, key := range keys {
+		if popped := s.pop(key, count, max); len(popped) > 0 {
+			s.Unlock()
+			return key, popped, nil
+		}
+	}
+	var popped []ScoreMember
+	w := s.blocked.block(keys, func(key string) bool {
+		popped = s.pop(key, count, max)
+		return len(popped) > 0
+	})
+	s.Unlock()
+
+	key, err := s.blocked.wait(ctx, s, w, timeout)
+	if err != nil || key == "" {
+		return "", nil, err
+	}
+	return key, popped, nil
+}
+
+// RandomMembers 随机返回成员及分数
+// allowRepeats 为 false 时返回至多 count 个不重复成员，否则返回恰好 count 个（至多 MaxRandomRepeats 个）可能重复的成员
+// 按跳表中的排名抽取，不需要复制整个有序集合
+func (s *ZSetStore) RandomMembers(key string, count int, allowRepeats bool) []ScoreMember {
+	s.RLock()
+	defer s.RUnlock()
+	zset, exists := s.m[key]
+	if !exists || count <= 0 {
+		return []ScoreMember{}
+	}
+	indexes := randomIndexes(len(zset.dict), count, allowRepeats)
+	result := make([]ScoreMember, len(indexes))
+	for i, j := range indexes {
+		x := zset.zsl.byRank(j + 1)
+		result[i] = ScoreMember{Member: x.member, Score: x.score}
+	}
+	return result
+}
+
+// Scan 按游标遍历成员，返回匹配的成员及分数和下一个游标
+// 索引按需建立，因此持有写锁
+func (s *ZSetStore) Scan(key string, cursor uint64, match string, count int) ([]ScoreMember, uint64) {
+	s.Lock()
+	defer s.Unlock()
+	zset, exists := s.m[key]
+	if !exists {
+		return []ScoreMember{}, 0
+	}
+	var matched []string
+	var next uint64
+	if len(zset.dict) <= scanSmallThreshold {
+		matched = scanAll(zset.members(), match)
+	} else {
+		if zset.scan == nil {
+			zset.scan = newScanIndex(zset.members())
+		}
+		matched, next = zset.scan.scan(cursor, match, count, nil)
+	}
+	result := make([]ScoreMember, len(matched))
+	for i, member := range matched {
+		result[i] = ScoreMember{Member: member, Score: zset.dict[member]}
+	}
+	return result, next
+}
+
+// ParseScore 解析分数，接受 inf/+inf/-inf，拒绝 NaN 和超出范围的数值
+func ParseScore(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}